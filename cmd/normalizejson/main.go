@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Grivn/normalizejson"
+)
+
+const (
+	exitOK            = 0
+	exitFailed        = 1 // some inputs fail to normalize, e.g. the validation failures.
+	exitUsage         = 2
+	exitNotNormalized = 3 // some inputs are not normalized in check mode.
+)
+
+const (
+	stdinName = "-"
+)
+
+var functionSets = map[string][]normalizejson.FormatOption{
+	"default":  normalizejson.DefaultFormatDataOptions,
+	"time":     normalizejson.TimeFormatDataOptions,
+	"decimal":  normalizejson.DecimalFormatDataOptions,
+	"string":   normalizejson.StringFormatDataOptions,
+	"bool":     normalizejson.BoolFormatDataOptions,
+	"mask":     normalizejson.MaskFormatDataOptions,
+	"validate": normalizejson.ValidateFormatDataOptions,
+	"encoding": normalizejson.EncodingFormatDataOptions,
+}
+
+var keyStyles = map[string]string{
+	"snake":                          normalizejson.FormatCamelToSnake,
+	"camel":                          normalizejson.FormatSnakeToCamel,
+	"screaming_snake":                normalizejson.FormatCamelToScreamingSnake,
+	normalizejson.FormatCamelToSnake: normalizejson.FormatCamelToSnake,
+	normalizejson.FormatSnakeToCamel: normalizejson.FormatSnakeToCamel,
+	normalizejson.FormatCamelToScreamingSnake: normalizejson.FormatCamelToScreamingSnake,
+	normalizejson.FormatScreamingSnakeToCamel: normalizejson.FormatScreamingSnakeToCamel,
+}
+
+type command struct {
+	template  string
+	schema    string
+	keys      string
+	functions string
+	ndjson    bool
+	lenient   bool
+	write     bool
+	check     bool
+	output    string
+	indent    string
+	workers   int
+
+	provider normalizejson.FormatProvider
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	cmd := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("normalizejson", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&cmd.template, "template", "", "the template file")
+	flags.StringVar(&cmd.schema, "schema", "", "the JSON Schema file to generate the template from, instead of -template")
+	flags.StringVar(&cmd.keys, "keys", "", "the key style: snake, camel, screaming_snake or the name of a built-in key function")
+	flags.StringVar(&cmd.functions, "functions", "default", "the comma-separated function sets: "+strings.Join(functionSetNames(), ", "))
+	flags.BoolVar(&cmd.ndjson, "ndjson", false, "take the inputs as newline-delimited JSON")
+	flags.BoolVar(&cmd.lenient, "lenient", false, "accept the JSON5 and JSONC syntax in the template and inputs")
+	flags.BoolVar(&cmd.write, "w", false, "write the result to the input files instead of stdout")
+	flags.BoolVar(&cmd.check, "check", false, "report the inputs which are not normalized without writing them")
+	flags.StringVar(&cmd.output, "output", "", "the output: compact, pretty, canonical or ordered, pretty with -indent and compact by default")
+	flags.StringVar(&cmd.indent, "indent", "", "the indent of output, compact by default")
+	flags.IntVar(&cmd.workers, "workers", 0, "the number of workers in NDJSON mode, the number of CPUs by default")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: normalizejson [flags] [file or glob ...]\n\nThe inputs are read from stdin without files.\n\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if err := cmd.prepare(); err != nil {
+		fmt.Fprintf(stderr, "normalizejson: %s\n", err)
+		return exitUsage
+	}
+
+	inputs, err := expandInputs(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "normalizejson: %s\n", err)
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, input := range inputs {
+		if input == stdinName && cmd.write {
+			fmt.Fprintf(stderr, "normalizejson: cannot write the result to stdin\n")
+			return exitUsage
+		}
+
+		inputCode := cmd.process(input)
+		if inputCode > code {
+			code = inputCode
+		}
+	}
+	return code
+}
+
+// prepare creates the provider with the template, key style and function sets.
+func (cmd *command) prepare() error {
+	var options []normalizejson.FormatOption
+	for _, name := range strings.Split(cmd.functions, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		set, ok := functionSets[name]
+		if !ok {
+			return fmt.Errorf("unknown function set %s", name)
+		}
+		options = append(options, set...)
+	}
+
+	if cmd.keys != "" {
+		funcName, ok := keyStyles[cmd.keys]
+		if !ok {
+			return fmt.Errorf("unknown key style %s", cmd.keys)
+		}
+		for _, option := range normalizejson.DefaultFormatKeyOptions {
+			if option.FunctionName == funcName {
+				options = append(options, option)
+			}
+		}
+	}
+
+	var template []byte
+	if cmd.template != "" && cmd.schema != "" {
+		return fmt.Errorf("-template and -schema are exclusive")
+	}
+	if cmd.template != "" {
+		var err error
+		if template, err = os.ReadFile(cmd.template); err != nil {
+			return fmt.Errorf("read template failed: %s", err)
+		}
+	}
+	if cmd.schema != "" {
+		schema, err := os.ReadFile(cmd.schema)
+		if err != nil {
+			return fmt.Errorf("read JSON Schema failed: %s", err)
+		}
+		if template, err = normalizejson.TemplateFromJSONSchema(schema); err != nil {
+			return err
+		}
+	}
+
+	output := cmd.output
+	switch output {
+	case "":
+		if cmd.indent != "" {
+			output = normalizejson.OutputPretty
+		}
+	case normalizejson.OutputCompact, normalizejson.OutputPretty, normalizejson.OutputCanonical, normalizejson.OutputOrdered:
+	default:
+		return fmt.Errorf("unknown output %s", output)
+	}
+
+	// keep the precision of numbers which are not normalized.
+	config := normalizejson.FormatConfig{UseNumber: true, Lenient: cmd.lenient, Output: output, Indent: cmd.indent}
+	provider, err := normalizejson.NewFormatProvider(template, append(options, normalizejson.FormatConfigOption(config))...)
+	if err != nil {
+		return fmt.Errorf("create provider failed: %s", err)
+	}
+	cmd.provider = provider
+	return nil
+}
+
+// process normalizes an input, and returns the exit code of it.
+func (cmd *command) process(input string) int {
+	data, err := cmd.read(input)
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "%s: %s\n", input, err)
+		return exitUsage
+	}
+
+	var formatted []byte
+	if cmd.ndjson {
+		formatted, err = cmd.formatNDJSON(input, data)
+	} else {
+		formatted, err = cmd.formatJSON(data)
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.stderr, "%s: %s\n", input, err)
+		return exitFailed
+	}
+
+	// the inputs are normalized only if they are the same bytes as the output, which "-w" would not rewrite.
+	if cmd.check {
+		if !bytes.Equal(data, formatted) {
+			fmt.Fprintf(cmd.stderr, "%s: not normalized\n", input)
+			return exitNotNormalized
+		}
+		return exitOK
+	}
+
+	if !cmd.write {
+		if _, err = cmd.stdout.Write(formatted); err != nil {
+			fmt.Fprintf(cmd.stderr, "%s: %s\n", input, err)
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	if bytes.Equal(data, formatted) {
+		return exitOK
+	}
+	if err = writeFile(input, formatted); err != nil {
+		fmt.Fprintf(cmd.stderr, "%s: %s\n", input, err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+func (cmd *command) read(input string) ([]byte, error) {
+	if input == stdinName {
+		return io.ReadAll(cmd.stdin)
+	}
+	return os.ReadFile(input)
+}
+
+func (cmd *command) formatJSON(data []byte) ([]byte, error) {
+	formatted, err := cmd.provider.FormatJSONSchema(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(formatted, '\n'), nil
+}
+
+// formatNDJSON normalizes the records, and reports the failed lines.
+func (cmd *command) formatNDJSON(input string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	config := normalizejson.BatchConfig{
+		Workers: cmd.workers,
+		OnError: func(err *normalizejson.BatchLineError) {
+			fmt.Fprintf(cmd.stderr, "%s:%d: %s\n", input, err.Line, err.Err)
+		},
+	}
+
+	result, err := normalizejson.FormatNDJSON(context.Background(), cmd.provider, bytes.NewReader(data), &buf, config)
+	if err != nil {
+		return nil, err
+	}
+	if result.Failed > 0 {
+		return nil, fmt.Errorf("%d of %d lines failed to normalize", result.Failed, result.Lines)
+	}
+	return buf.Bytes(), nil
+}
+
+// expandInputs expands the globs in args, the inputs are stdin without args.
+func expandInputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{stdinName}, nil
+	}
+
+	var inputs []string
+	for _, arg := range args {
+		if arg == stdinName || !strings.ContainsAny(arg, "*?[") {
+			inputs = append(inputs, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("illegal glob %s: %s", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %s", arg)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// writeFile replaces the file with data atomically, by renaming a temp file in the same directory, and keeps the mode of it.
+func writeFile(name string, data []byte) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// the temp file is removed if anything fails before renaming.
+	defer os.Remove(temp.Name())
+
+	if _, err = temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err = temp.Chmod(info.Mode().Perm()); err != nil {
+		temp.Close()
+		return err
+	}
+	if err = temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), name)
+}
+
+func functionSetNames() []string {
+	names := make([]string, 0, len(functionSets))
+	for name := range functionSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
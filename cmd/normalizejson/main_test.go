@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runCommand runs the command with stdin, and returns the exit code, stdout and stderr.
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeTestFile(dir string, name string, data string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0640); err != nil {
+		panic(err)
+	}
+	return path
+}
+
+func readTestFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func TestRunStdin(t *testing.T) {
+	dir := t.TempDir()
+	template := writeTestFile(dir, "template.json", `{"user_id":"to_int64"}`)
+
+	code, stdout, stderr := runCommand(`{"userId":"1","amount":1.50}`, "-keys", "snake", "-template", template)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "{\"amount\":1.50,\"user_id\":1}\n", stdout)
+
+	code, stdout, _ = runCommand(`{"b":1,"a":[2]}`, "-indent", "  ")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "{\n  \"a\": [\n    2\n  ],\n  \"b\": 1\n}\n", stdout)
+
+	code, stdout, _ = runCommand(`{"b":1,"a":2}`, "-output", "ordered")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "{\"b\":1,\"a\":2}\n", stdout)
+
+	code, stdout, _ = runCommand(`{b: 1, /* comment */ 'a': 2,}`, "-lenient")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "{\"a\":2,\"b\":1}\n", stdout)
+
+	code, stdout, stderr = runCommand("{\"age\":\"1\"}\n{\"age\":\"x\"}\n", "-ndjson", "-template", writeTestFile(dir, "age.json", `{"age":"to_int64"}`))
+	assert.Equal(t, exitFailed, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "-:2:")
+}
+
+func TestRunSchema(t *testing.T) {
+	dir := t.TempDir()
+	schema := writeTestFile(dir, "schema.json", `{"type":"object","properties":{"id":{"type":"integer"},"tags":{"type":"array","items":{"type":"string"}}},"required":["id"]}`)
+
+	code, stdout, stderr := runCommand(`{"id":"7","tags":[1]}`, "-schema", schema)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "{\"id\":7,\"tags\":[\"1\"]}\n", stdout)
+
+	code, _, _ = runCommand(`{"id":null}`, "-schema", schema)
+	assert.Equal(t, exitFailed, code)
+}
+
+func TestRunCheck(t *testing.T) {
+	dir := t.TempDir()
+	template := writeTestFile(dir, "template.json", `{"count":"to_int64"}`)
+	normalized := writeTestFile(dir, "normalized.json", "{\"count\":1}\n")
+	unnormalized := writeTestFile(dir, "unnormalized.json", `{"count":"1"}`)
+	// the same values in another layout would be rewritten by "-w".
+	indented := writeTestFile(dir, "indented.json", "{\n  \"count\": 1\n}\n")
+
+	code, stdout, stderr := runCommand("", "-check", "-template", template, normalized)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Empty(t, stdout)
+
+	code, _, stderr = runCommand("", "-check", "-template", template, normalized, unnormalized, indented)
+	assert.Equal(t, exitNotNormalized, code)
+	assert.Equal(t, unnormalized+": not normalized\n"+indented+": not normalized\n", stderr)
+
+	code, _, stderr = runCommand("", "-check", "-output", "pretty", "-template", template, indented)
+	assert.Equal(t, exitOK, code, stderr)
+
+	// the files are never written in check mode.
+	assert.Equal(t, `{"count":"1"}`, readTestFile(unnormalized))
+}
+
+func TestRunWrite(t *testing.T) {
+	dir := t.TempDir()
+	template := writeTestFile(dir, "template.json", `{"count":"to_int64"}`)
+	first := writeTestFile(dir, "first.data.json", `{"count":"1"}`)
+	second := writeTestFile(dir, "second.data.json", "{\"count\":2}\n")
+	if err := os.Chmod(second, 0600); err != nil {
+		panic(err)
+	}
+
+	code, stdout, stderr := runCommand("", "-w", "-template", template, filepath.Join(dir, "*.data.json"))
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Empty(t, stdout)
+	assert.Equal(t, "{\"count\":1}\n", readTestFile(first))
+	assert.Equal(t, "{\"count\":2}\n", readTestFile(second))
+
+	// the mode is kept, and no temp file is left.
+	info, err := os.Stat(first)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	if err = os.WriteFile(second, []byte(`{"count":"3"}`), 0600); err != nil {
+		panic(err)
+	}
+	code, _, stderr = runCommand("", "-w", "-template", template, second)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "{\"count\":3}\n", readTestFile(second))
+	if info, err = os.Stat(second); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+	assert.Len(t, entries, 3)
+
+	// the failed file is left as it is.
+	failed := writeTestFile(dir, "failed.json", `{"count":"x"}`)
+	code, _, _ = runCommand("", "-w", "-template", template, failed)
+	assert.Equal(t, exitFailed, code)
+	assert.Equal(t, `{"count":"x"}`, readTestFile(failed))
+}
+
+func TestRunUsage(t *testing.T) {
+	dir := t.TempDir()
+	template := writeTestFile(dir, "template.json", `{}`)
+
+	tests := []struct {
+		stdin string
+		args  []string
+	}{
+		{"", []string{"-unknown"}},
+		{"", []string{"-keys", "kebab"}},
+		{"", []string{"-functions", "default,unknown"}},
+		{"", []string{"-output", "yaml"}},
+		{"", []string{"-template", filepath.Join(dir, "missing.json")}},
+		{"", []string{"-template", template, "-schema", template}},
+		{"", []string{filepath.Join(dir, "*.yaml")}},
+		{"", []string{filepath.Join(dir, "missing.json")}},
+		{"{}", []string{"-w"}},
+	}
+
+	for _, test := range tests {
+		code, stdout, stderr := runCommand(test.stdin, test.args...)
+		assert.Equal(t, exitUsage, code, test.args)
+		assert.Empty(t, stdout, test.args)
+		assert.NotEmpty(t, stderr, test.args)
+	}
+
+	code, _, stderr := runCommand("", "-h")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "usage: normalizejson")
+
+	code, _, _ = runCommand(`{broken`)
+	assert.Equal(t, exitFailed, code)
+}
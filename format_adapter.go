@@ -0,0 +1,84 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The helpers of the adapters which normalize the other formats than JSON with providers, and write them back.
+
+// matchFormattedKey finds the formatted key of the original one, which is the same key or the only one equal to it
+// ignoring case and separators.
+func matchFormattedKey(original string, keys []string, used map[string]bool) (string, bool) {
+	var matched []string
+	folded := foldFormattedKey(original)
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		if key == original {
+			return key, true
+		}
+		if foldFormattedKey(key) == folded {
+			matched = append(matched, key)
+		}
+	}
+
+	if len(matched) == 1 {
+		return matched[0], true
+	}
+	return "", false
+}
+
+var keySeparatorReplacer = strings.NewReplacer("_", "", "-", "", " ", "", ".", "")
+
+func foldFormattedKey(key string) string {
+	return strings.ToLower(keySeparatorReplacer.Replace(key))
+}
+
+// formatValueByProvider normalizes the JSON value with provider, the numbers of result are json.Number.
+func formatValueByProvider(provider FormatProvider, value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal source data failed: %s", err)
+	}
+
+	formatted, err := formatJSONExactly(provider, data)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalJSON(formatted, FormatConfig{UseNumber: true})
+}
+
+// formatJSONExactly normalizes the JSON data with provider, which decodes the numbers as json.Number whatever its config
+// is, so that the integers beyond 2^53 in the other formats are not rounded. The providers out of this package are
+// expected to keep the numbers by themselves.
+func formatJSONExactly(provider FormatProvider, data []byte) ([]byte, error) {
+	switch p := provider.(type) {
+	case *formatSchemaImpl:
+		exact := *p
+		exact.config.UseNumber = true
+		return exact.FormatJSONSchema(data)
+	case *formatDataImpl:
+		exact := *p
+		exact.config.UseNumber = true
+		return exact.FormatJSONSchema(data)
+	case *formatKeyImpl:
+		exact := *p
+		exact.config.UseNumber = true
+		return exact.FormatJSONSchema(data)
+	default:
+		return provider.FormatJSONSchema(data)
+	}
+}
+
+func sortedKeys(itemMap map[string]interface{}) []string {
+	keys := make([]string, 0, len(itemMap))
+	for key := range itemMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
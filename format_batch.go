@@ -0,0 +1,192 @@
+package normalizejson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+const (
+	DefaultBatchMaxLineSize = 16 * 1024 * 1024
+)
+
+// BatchConfig configures the batch processing of NDJSON, the zero value of each field takes the default one.
+type BatchConfig struct {
+	Workers     int                       // the number of workers, runtime.NumCPU() by default.
+	MaxLineSize int                       // the max size of a line in bytes, DefaultBatchMaxLineSize by default, and the longer lines are errors.
+	KeepFailed  bool                      // write the original lines which fail to normalize, instead of skipping them.
+	OnError     func(err *BatchLineError) // receive the errors of lines in order, instead of collecting them in BatchResult.
+}
+
+// BatchResult is the summary of the batch processing.
+type BatchResult struct {
+	Lines  int               // the number of non-empty lines.
+	Failed int               // the number of lines which fail to normalize.
+	Errors []*BatchLineError // the errors of lines, which are collected without BatchConfig.OnError.
+}
+
+// BatchLineError is the error of a line in NDJSON, Path is the path of the value if the error is a FormatError.
+type BatchLineError struct {
+	Line int
+	Path string
+	Err  error
+}
+
+func (e *BatchLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *BatchLineError) Unwrap() error {
+	return e.Err
+}
+
+type batchJob struct {
+	line   int
+	data   []byte
+	result []byte
+	err    error
+	done   chan struct{}
+}
+
+// FormatNDJSON reads the newline-delimited JSON records from r, normalizes them with provider across a pool of workers,
+// and writes them to w in the input order. The failed lines are reported without stopping the batch, and the returned
+// error is only for reading, writing and the cancellation of ctx. The provider should not be updated during the batch.
+func FormatNDJSON(ctx context.Context, provider FormatProvider, r io.Reader, w io.Writer, config BatchConfig) (BatchResult, error) {
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.MaxLineSize <= 0 {
+		config.MaxLineSize = DefaultBatchMaxLineSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *batchJob, config.Workers)
+	queue := make(chan *batchJob, config.Workers*4)
+
+	// the reader dispatches the lines to the workers, and queues them to be written in order.
+	var readErr error
+	go func() {
+		defer close(queue)
+		defer close(jobs)
+
+		reader := bufio.NewReaderSize(r, 64*1024)
+		for line := 1; ; line++ {
+			if ctx.Err() != nil {
+				return
+			}
+
+			data, tooLong, err := readNDJSONLine(reader, config.MaxLineSize)
+			if tooLong {
+				// the oversized line fails alone, and the batch goes on.
+				job := &batchJob{line: line, err: fmt.Errorf("%w: exceeds %d bytes", bufio.ErrTooLong, config.MaxLineSize), done: make(chan struct{})}
+				close(job.done)
+				queue <- job
+			} else if data = bytes.TrimSpace(data); len(data) > 0 {
+				job := &batchJob{line: line, data: append([]byte(nil), data...), done: make(chan struct{})}
+				queue <- job
+				jobs <- job
+			}
+
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr = fmt.Errorf("read NDJSON failed: %s", err)
+				}
+				return
+			}
+		}
+	}()
+
+	for index := 0; index < config.Workers; index++ {
+		go func() {
+			for job := range jobs {
+				job.result, job.err = formatJSONSchemaContext(ctx, provider, job.data)
+				close(job.done)
+			}
+		}()
+	}
+
+	var result BatchResult
+	var writeErr error
+	for job := range queue {
+		<-job.done
+		if writeErr != nil {
+			continue
+		}
+
+		result.Lines++
+		output := job.result
+		if job.err != nil {
+			result.Failed++
+			lineErr := &BatchLineError{Line: job.line, Err: job.err}
+			var formatErr *FormatError
+			if errors.As(job.err, &formatErr) {
+				lineErr.Path = formatErr.Path
+			}
+			if config.OnError != nil {
+				config.OnError(lineErr)
+			} else {
+				result.Errors = append(result.Errors, lineErr)
+			}
+
+			// the oversized lines are not kept, which have been discarded in reading.
+			if !config.KeepFailed || job.data == nil {
+				continue
+			}
+			output = job.data
+		}
+
+		if _, err := w.Write(append(ndjsonLine(output), '\n')); err != nil {
+			// stop reading, and drain the queued lines.
+			writeErr = fmt.Errorf("write NDJSON failed: %s", err)
+			cancel()
+		}
+	}
+
+	if writeErr != nil {
+		return result, writeErr
+	}
+	if readErr != nil {
+		return result, readErr
+	}
+	return result, ctx.Err()
+}
+
+// readNDJSONLine reads a line from reader, the line longer than maxSize is discarded and reported as too long.
+func readNDJSONLine(reader *bufio.Reader, maxSize int) ([]byte, bool, error) {
+	var data []byte
+	var tooLong bool
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			data = append(data, chunk...)
+			if len(bytes.TrimSuffix(data, []byte("\n"))) > maxSize {
+				data, tooLong = nil, true
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		return data, tooLong, err
+	}
+}
+
+// ndjsonLine keeps the output in one line, e.g. with the pretty output or the trailing newline.
+func ndjsonLine(output []byte) []byte {
+	output = bytes.TrimRight(output, "\n")
+	if bytes.IndexByte(output, '\n') < 0 {
+		return output
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, output); err != nil {
+		return output
+	}
+	return buf.Bytes()
+}
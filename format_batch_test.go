@@ -0,0 +1,100 @@
+package normalizejson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatNDJSON(t *testing.T) {
+	provider, err := NewFormatProvider([]byte(`{"age":"to_int64","email":"email"}`), append(append([]FormatOption{}, DefaultFormatDataOptions...), ValidateFormatDataOptions...)...)
+	if err != nil {
+		panic(err)
+	}
+
+	var source, result strings.Builder
+	for index := 0; index < 1000; index++ {
+		if index%100 == 7 {
+			source.WriteString(fmt.Sprintf("{\"id\":%d,\"email\":\"not an email\"}\n", index))
+			continue
+		}
+		if index%100 == 8 {
+			source.WriteString("\r\n")
+		}
+		source.WriteString(fmt.Sprintf("{\"id\":%d,\"age\":\"%d\",\"email\":\"User%d@Example.com\"}\n", index, index%90, index))
+		result.WriteString(fmt.Sprintf("{\"age\":%d,\"email\":\"user%d@example.com\",\"id\":%d}\n", index%90, index, index))
+	}
+
+	var output bytes.Buffer
+	batch, err := FormatNDJSON(context.Background(), provider, strings.NewReader(source.String()), &output, BatchConfig{Workers: 8})
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, result.String(), output.String())
+	assert.Equal(t, 1000, batch.Lines)
+	assert.Equal(t, 10, batch.Failed)
+	if assert.Len(t, batch.Errors, 10) {
+		assert.Equal(t, 8, batch.Errors[0].Line)
+		assert.Equal(t, "$.email", batch.Errors[0].Path)
+		assert.Equal(t, 109, batch.Errors[1].Line)
+	}
+}
+
+func TestFormatNDJSONKeepFailed(t *testing.T) {
+	provider, err := NewFormatProvider([]byte(`{"age":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	var lines []int
+	var output bytes.Buffer
+	source := "{\"age\":\"1\"}\n{\"age\":\"x\"}\n{broken\n{\"age\":2.0}"
+	batch, err := FormatNDJSON(context.Background(), provider, strings.NewReader(source), &output, BatchConfig{
+		KeepFailed: true,
+		OnError: func(err *BatchLineError) {
+			lines = append(lines, err.Line)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, "{\"age\":1}\n{\"age\":\"x\"}\n{broken\n{\"age\":2}\n", output.String())
+	assert.Equal(t, []int{2, 3}, lines)
+	assert.Equal(t, 2, batch.Failed)
+	assert.Empty(t, batch.Errors)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = FormatNDJSON(ctx, provider, strings.NewReader(source), &output, BatchConfig{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFormatNDJSONLineTooLong(t *testing.T) {
+	provider, err := NewFormatProvider([]byte(`{"age":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	// the oversized line fails alone, and the lines after it are still formatted.
+	var output bytes.Buffer
+	source := "{\"age\":\"1\"}\n{\"name\":\"" + strings.Repeat("x", 200) + "\"}\n{\"age\":\"3\"}\n"
+	batch, err := FormatNDJSON(context.Background(), provider, strings.NewReader(source), &output, BatchConfig{MaxLineSize: 64, KeepFailed: true})
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, "{\"age\":1}\n{\"age\":3}\n", output.String())
+	assert.Equal(t, 3, batch.Lines)
+	assert.Equal(t, 1, batch.Failed)
+	if assert.Len(t, batch.Errors, 1) {
+		assert.Equal(t, 2, batch.Errors[0].Line)
+		assert.ErrorIs(t, batch.Errors[0].Err, bufio.ErrTooLong)
+	}
+}
@@ -0,0 +1,365 @@
+package normalizejson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// FormatMsgpack normalizes the MessagePack value with provider, so that the same templates and options work for it.
+// The byte strings are taken as base64 strings, and the timestamps as RFC3339 strings, which are written back as byte
+// strings and timestamps if they are still valid. The integers are written back in the widths of the original ones,
+// e.g. uint64 keeps the values beyond int64, while the new values take int64, uint64 or float64.
+func FormatMsgpack(provider FormatProvider, data []byte) ([]byte, error) {
+	original, err := unmarshalMsgpack(data)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := formatBinaryValue(provider, original)
+	if err != nil {
+		return nil, err
+	}
+	return marshalMsgpack(binaryValue(value, original))
+}
+
+// FormatCBOR normalizes the CBOR value with provider as FormatMsgpack does. The map keys are sorted in the result.
+func FormatCBOR(provider FormatProvider, data []byte) ([]byte, error) {
+	original, err := unmarshalCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := formatBinaryValue(provider, original)
+	if err != nil {
+		return nil, err
+	}
+	return marshalCBOR(binaryValue(value, original))
+}
+
+// MsgpackToJSON converts the MessagePack value to JSON, in which the byte strings are base64 strings.
+func MsgpackToJSON(data []byte) ([]byte, error) {
+	value, err := unmarshalMsgpack(data)
+	if err != nil {
+		return nil, err
+	}
+	return marshalBinaryJSON(value)
+}
+
+// CBORToJSON converts the CBOR value to JSON, in which the byte strings are base64 strings.
+func CBORToJSON(data []byte) ([]byte, error) {
+	value, err := unmarshalCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	return marshalBinaryJSON(value)
+}
+
+// JSONToMsgpack converts the JSON value to MessagePack, the integers take int64 or uint64.
+func JSONToMsgpack(data []byte) ([]byte, error) {
+	value, err := unmarshalJSON(data, FormatConfig{UseNumber: true})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	}
+	return marshalMsgpack(binaryValue(value, nil))
+}
+
+// JSONToCBOR converts the JSON value to CBOR, the integers take int64 or uint64.
+func JSONToCBOR(data []byte) ([]byte, error) {
+	value, err := unmarshalJSON(data, FormatConfig{UseNumber: true})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	}
+	return marshalCBOR(binaryValue(value, nil))
+}
+
+func unmarshalMsgpack(data []byte) (interface{}, error) {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	// take the maps with non-string keys.
+	decoder.SetMapDecoder(func(d *msgpack.Decoder) (interface{}, error) {
+		return d.DecodeUntypedMap()
+	})
+
+	value, err := decoder.DecodeInterface()
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source MessagePack failed: %s", err)
+	}
+	return value, nil
+}
+
+func marshalMsgpack(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetSortMapKeys(true)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("marshal MessagePack failed: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var cborEncMode = func() cbor.EncMode {
+	mode, err := cbor.EncOptions{Sort: cbor.SortCoreDeterministic, Time: cbor.TimeRFC3339Nano, TimeTag: cbor.EncTagRequired}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+func unmarshalCBOR(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := cbor.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("unmarshal source CBOR failed: %s", err)
+	}
+	return value, nil
+}
+
+func marshalCBOR(value interface{}) ([]byte, error) {
+	data, err := cborEncMode.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal CBOR failed: %s", err)
+	}
+	return data, nil
+}
+
+func formatBinaryValue(provider FormatProvider, original interface{}) (interface{}, error) {
+	value, err := binaryToJSONValue(original)
+	if err != nil {
+		return nil, err
+	}
+	return formatValueByProvider(provider, value)
+}
+
+func marshalBinaryJSON(value interface{}) ([]byte, error) {
+	converted, err := binaryToJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(converted)
+	if err != nil {
+		return nil, fmt.Errorf("marshal JSON failed: %s", err)
+	}
+	return data, nil
+}
+
+// binaryToJSONValue converts the value decoded from MessagePack or CBOR to the JSON value model, in which the numbers
+// are json.Number to keep their precision.
+func binaryToJSONValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, string:
+		return v, nil
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			convertedItem, err := binaryToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			converted[key] = convertedItem
+		}
+		return converted, nil
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			convertedKey, err := binaryMapKey(key)
+			if err != nil {
+				return nil, err
+			}
+			convertedItem, err := binaryToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			converted[convertedKey] = convertedItem
+		}
+		return converted, nil
+	case []interface{}:
+		converted := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			convertedItem, err := binaryToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, convertedItem)
+		}
+		return converted, nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return json.Number(fmt.Sprint(v)), nil
+	case *big.Int:
+		return json.Number(v.String()), nil
+	case float32:
+		if math.IsInf(float64(v), 0) || math.IsNaN(float64(v)) {
+			return nil, fmt.Errorf("unsupported value %v", v)
+		}
+		return json.Number(strconv.FormatFloat(float64(v), 'g', -1, 32)), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("unsupported value %v", v)
+		}
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v of type %T", v, v)
+	}
+}
+
+func binaryMapKey(key interface{}) (string, error) {
+	switch k := key.(type) {
+	case nil:
+		return "null", nil
+	case string, bool, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(k), nil
+	case []byte:
+		return string(k), nil
+	default:
+		return "", fmt.Errorf("unsupported map key %v of type %T", k, k)
+	}
+}
+
+// binaryValue converts the JSON value to the value to be encoded, and takes the types of the original values which
+// still fit, e.g. the byte strings, timestamps and the widths of integers. The original value may be nil.
+func binaryValue(value interface{}, original interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		originalMap := binaryOriginalMap(original)
+		originalKeys := sortedKeys(originalMap)
+		converted := make(map[string]interface{}, len(v))
+		used := make(map[string]bool, len(originalKeys))
+		for key, item := range v {
+			var originalItem interface{}
+			if originalKey, ok := matchFormattedKey(key, originalKeys, used); ok {
+				used[originalKey] = true
+				originalItem = originalMap[originalKey]
+			}
+			converted[key] = binaryValue(item, originalItem)
+		}
+		return converted
+	case []interface{}:
+		originalList, _ := original.([]interface{})
+		if len(originalList) != len(v) {
+			originalList = nil
+		}
+		converted := make([]interface{}, 0, len(v))
+		for index, item := range v {
+			var originalItem interface{}
+			if originalList != nil {
+				originalItem = originalList[index]
+			}
+			converted = append(converted, binaryValue(item, originalItem))
+		}
+		return converted
+	case string:
+		switch original.(type) {
+		case []byte:
+			if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+				return decoded
+			}
+		case time.Time:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t
+			}
+		}
+		return v
+	case json.Number:
+		return binaryNumber(v, original)
+	default:
+		return v
+	}
+}
+
+func binaryOriginalMap(original interface{}) map[string]interface{} {
+	switch v := original.(type) {
+	case map[string]interface{}:
+		return v
+	case map[interface{}]interface{}:
+		originalMap := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if convertedKey, err := binaryMapKey(key); err == nil {
+				originalMap[convertedKey] = item
+			}
+		}
+		return originalMap
+	default:
+		return nil
+	}
+}
+
+// binaryNumber takes the type of the original number if the value fits it.
+func binaryNumber(n json.Number, original interface{}) interface{} {
+	// the numbers unchanged are taken as they are, which are decoded as json.Number by the provider.
+	if originalNumber, err := binaryToJSONValue(original); err == nil {
+		if o, ok := originalNumber.(json.Number); ok && o == n {
+			return original
+		}
+	}
+
+	switch original.(type) {
+	case float32:
+		if f, err := strconv.ParseFloat(n.String(), 32); err == nil {
+			return float32(f)
+		}
+	case float64:
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+
+	if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+		switch original.(type) {
+		case int8:
+			if i >= math.MinInt8 && i <= math.MaxInt8 {
+				return int8(i)
+			}
+		case int16:
+			if i >= math.MinInt16 && i <= math.MaxInt16 {
+				return int16(i)
+			}
+		case int32:
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return int32(i)
+			}
+		case uint8:
+			if i >= 0 && i <= math.MaxUint8 {
+				return uint8(i)
+			}
+		case uint16:
+			if i >= 0 && i <= math.MaxUint16 {
+				return uint16(i)
+			}
+		case uint32:
+			if i >= 0 && i <= math.MaxUint32 {
+				return uint32(i)
+			}
+		case uint64:
+			if i >= 0 {
+				return uint64(i)
+			}
+		}
+		return i
+	}
+
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u
+	}
+	if _, ok := original.(*big.Int); ok {
+		if bi, ok := new(big.Int).SetString(n.String(), 10); ok {
+			return bi
+		}
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return n.String()
+	}
+	return f
+}
@@ -0,0 +1,144 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestFormatMsgpack(t *testing.T) {
+	source, err := msgpack.Marshal(map[string]interface{}{
+		"userId":    uint64(math.MaxUint64),
+		"retry":     int16(3),
+		"signature": []byte{0, 1, 2, 255},
+		"count":     "12",
+		"createdAt": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider([]byte(`{"__keys":"camel_to_snake","count":"to_int64","retry":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatMsgpack(provider, source)
+	if err != nil {
+		panic(err)
+	}
+
+	var result map[string]interface{}
+	if err = msgpack.Unmarshal(formatted, &result); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"user_id":    uint64(math.MaxUint64),
+		"retry":      int16(3),
+		"signature":  []byte{0, 1, 2, 255},
+		"count":      int64(12),
+		"created_at": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Local(),
+	}, result)
+
+	converted, err := MsgpackToJSON(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"userId":18446744073709551615,"retry":3,"signature":"AAEC/w==","count":"12","createdAt":"2024-01-02T03:04:05Z"}`)), formatJSON(converted))
+}
+
+func TestFormatCBOR(t *testing.T) {
+	source, err := cbor.Marshal(map[interface{}]interface{}{
+		"userId":  uint64(math.MaxUint64),
+		"balance": int64(-5),
+		"payload": []byte("hello"),
+		"rate":    "1.5",
+		1:         "one",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider([]byte(`{"__keys":"camel_to_snake","rate":"to_float64","payload":"base64_decode"}`), append(DefaultFormatDataOptions, EncodingFormatDataOptions...)...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatCBOR(provider, source)
+	if err != nil {
+		panic(err)
+	}
+
+	var result interface{}
+	if err = cbor.Unmarshal(formatted, &result); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, map[interface{}]interface{}{
+		"user_id": uint64(math.MaxUint64),
+		"balance": int64(-5),
+		"payload": "hello",
+		"rate":    1.5,
+		"1":       "one",
+	}, result)
+
+	// the byte string decoded by template is written as a text string, and the keys are sorted.
+	assert.Equal(t, mustJSONToCBOR(`{"user_id":18446744073709551615,"rate":1.5,"payload":"hello","balance":-5,"1":"one"}`), formatted)
+
+	_, err = CBORToJSON([]byte{0xff})
+	assert.NotNil(t, err)
+}
+
+func mustJSONToCBOR(data string) []byte {
+	converted, err := JSONToCBOR([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return converted
+}
+
+func TestFormatMsgpackLargeIntegers(t *testing.T) {
+	source, err := msgpack.Marshal(map[string]interface{}{
+		"unsigned": uint64(9007199254740992),
+		"signed":   int64(-9007199254740992),
+		"same":     uint64(9007199254740993),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// the integers beyond the precision of float64 are compared exactly whatever the config of provider is, so that
+	// the changed ones are written.
+	increase := FormatOption{FunctionName: "increase", FormatFunction: func(value interface{}) (interface{}, error) {
+		i, ok := new(big.Int).SetString(fmt.Sprint(value), 10)
+		if !ok {
+			return value, fmt.Errorf("illegal integer %v", value)
+		}
+		return json.Number(i.Add(i, big.NewInt(1)).String()), nil
+	}}
+	provider, err := NewFormatProvider([]byte(`{"unsigned":"increase","signed":"increase"}`), increase)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatMsgpack(provider, source)
+	if err != nil {
+		panic(err)
+	}
+
+	var result map[string]interface{}
+	if err = msgpack.Unmarshal(formatted, &result); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"unsigned": uint64(9007199254740993),
+		"signed":   int64(-9007199254740991),
+		"same":     uint64(9007199254740993),
+	}, result)
+}
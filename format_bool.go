@@ -0,0 +1,196 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatParseBool = "parse_bool"
+)
+
+const (
+	BoolNumericZeroOne = "zero_one" // 0 is false and 1 is true, the other numbers are error.
+	BoolNumericNonZero = "non_zero" // 0 is false and the other numbers are true.
+	BoolNumericReject  = "reject"   // all the numbers are error.
+
+	BoolMissingNull  = "null"  // retain as null.
+	BoolMissingFalse = "false" // regard as false.
+	BoolMissingError = "error" // regard as error.
+)
+
+var (
+	DefaultBoolTruthy = []string{"true", "t", "yes", "y", "on", "1", "enabled", "enable"}
+	DefaultBoolFalsy  = []string{"false", "f", "no", "n", "off", "0", "disabled", "disable"}
+)
+
+// BoolConfig configures how to coerce a value to bool, the zero value of each field takes the default one.
+type BoolConfig struct {
+	Truthy        []string // the strings regarded as true, DefaultBoolTruthy by default.
+	Falsy         []string // the strings regarded as false, DefaultBoolFalsy by default.
+	CaseSensitive bool     // compare the strings case-sensitively.
+	Numeric       string   // the policy for numbers, BoolNumericZeroOne by default.
+	Empty         string   // the policy for empty string, BoolMissingNull by default.
+	Null          string   // the policy for null, BoolMissingNull by default.
+}
+
+// BoolFormatDataOptions are the data-options to coerce values to bool with the vocabularies of legacy systems.
+//
+// The function "parse_bool" takes the parameters "truthy", "falsy", "case_sensitive", "numeric", "empty" and "null"
+// in function leaf of template, which are the same as the fields of BoolConfig.
+//
+// E.g. {"__function":"parse_bool","truthy":["Y"],"falsy":["N"],"case_sensitive":true,"empty":"false"}.
+var BoolFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatParseBool, BuildFormatDataParseBool),
+}
+
+// FormatBoolOption creates a data-option named funcName to coerce values to bool with the config.
+func FormatBoolOption(funcName string, config BoolConfig) (FormatOption, error) {
+	bc, err := newBoolCoercer(config)
+	if err != nil {
+		return FormatOption{}, err
+	}
+	return FormatDataOption(funcName, bc.format), nil
+}
+
+func BuildFormatDataParseBool(params map[string]interface{}) (FormatFunc, error) {
+	var config BoolConfig
+	var err error
+
+	if config.Truthy, err = paramStringList(params, "truthy", nil); err != nil {
+		return nil, err
+	}
+	if config.Falsy, err = paramStringList(params, "falsy", nil); err != nil {
+		return nil, err
+	}
+	if config.CaseSensitive, err = paramBool(params, "case_sensitive", false); err != nil {
+		return nil, err
+	}
+	if config.Numeric, err = paramString(params, "numeric", ""); err != nil {
+		return nil, err
+	}
+	if config.Empty, err = paramString(params, "empty", ""); err != nil {
+		return nil, err
+	}
+	if config.Null, err = paramString(params, "null", ""); err != nil {
+		return nil, err
+	}
+
+	bc, err := newBoolCoercer(config)
+	if err != nil {
+		return nil, err
+	}
+	return bc.format, nil
+}
+
+type boolCoercer struct {
+	config BoolConfig
+	values map[string]bool
+}
+
+func newBoolCoercer(config BoolConfig) (*boolCoercer, error) {
+	if len(config.Truthy) == 0 {
+		config.Truthy = DefaultBoolTruthy
+	}
+	if len(config.Falsy) == 0 {
+		config.Falsy = DefaultBoolFalsy
+	}
+	if config.Numeric == "" {
+		config.Numeric = BoolNumericZeroOne
+	}
+	if config.Empty == "" {
+		config.Empty = BoolMissingNull
+	}
+	if config.Null == "" {
+		config.Null = BoolMissingNull
+	}
+
+	switch config.Numeric {
+	case BoolNumericZeroOne, BoolNumericNonZero, BoolNumericReject:
+	default:
+		return nil, fmt.Errorf("unknown numeric policy %s", config.Numeric)
+	}
+
+	for _, policy := range []string{config.Empty, config.Null} {
+		switch policy {
+		case BoolMissingNull, BoolMissingFalse, BoolMissingError:
+		default:
+			return nil, fmt.Errorf("unknown missing value policy %s", policy)
+		}
+	}
+
+	bc := &boolCoercer{config: config, values: make(map[string]bool)}
+	for _, str := range config.Truthy {
+		bc.values[bc.fold(str)] = true
+	}
+	for _, str := range config.Falsy {
+		folded := bc.fold(str)
+		if _, ok := bc.values[folded]; ok {
+			return nil, fmt.Errorf("%s is both truthy and falsy", str)
+		}
+		bc.values[folded] = false
+	}
+	return bc, nil
+}
+
+func (bc *boolCoercer) format(item interface{}) (interface{}, error) {
+	switch v := item.(type) {
+	case nil:
+		return bc.formatMissing(item, bc.config.Null)
+	case bool:
+		return v, nil
+	case string:
+		str := strings.TrimSpace(v)
+		if str == "" {
+			return bc.formatMissing(item, bc.config.Empty)
+		}
+		if b, ok := bc.values[bc.fold(str)]; ok {
+			return b, nil
+		}
+		return item, fmt.Errorf("unable to cast %#v of type %T to bool", item, item)
+	case json.Number:
+		return bc.formatNumber(item, v.String())
+	case float64:
+		return bc.formatNumber(item, strconv.FormatFloat(v, 'f', -1, 64))
+	case float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return bc.formatNumber(item, fmt.Sprint(v))
+	default:
+		return item, fmt.Errorf("unable to cast %#v of type %T to bool", item, item)
+	}
+}
+
+func (bc *boolCoercer) formatNumber(item interface{}, str string) (interface{}, error) {
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return item, fmt.Errorf("unable to cast %#v of type %T to bool", item, item)
+	}
+
+	switch {
+	case bc.config.Numeric == BoolNumericNonZero:
+		return f != 0, nil
+	case bc.config.Numeric == BoolNumericZeroOne && (f == 0 || f == 1):
+		return f == 1, nil
+	default:
+		return item, fmt.Errorf("unable to cast %#v of type %T to bool", item, item)
+	}
+}
+
+func (bc *boolCoercer) formatMissing(item interface{}, policy string) (interface{}, error) {
+	switch policy {
+	case BoolMissingFalse:
+		return false, nil
+	case BoolMissingError:
+		return item, fmt.Errorf("unable to cast %#v of type %T to bool", item, item)
+	default:
+		return nil, nil
+	}
+}
+
+func (bc *boolCoercer) fold(str string) string {
+	if bc.config.CaseSensitive {
+		return str
+	}
+	return strings.ToLower(str)
+}
@@ -0,0 +1,47 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatBool(t *testing.T) {
+	dir := "format_bool"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	legacyOption, err := FormatBoolOption("legacy_bool", BoolConfig{Truthy: []string{"T"}, Falsy: []string{"F"}})
+	if err != nil {
+		panic(err)
+	}
+
+	options := append(BoolFormatDataOptions, legacyOption)
+	formatted, err := JSONSchemaFormat(source, template, options...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	_, err = JSONSchemaFormat([]byte(`{"users":[{"active":"maybe"}]}`), template, options...)
+	assert.NotNil(t, err)
+
+	_, err = JSONSchemaFormat([]byte(`{"users":[{"active":2}]}`), template, options...)
+	assert.NotNil(t, err)
+
+	_, err = FormatBoolOption("illegal_bool", BoolConfig{Truthy: []string{"x"}, Falsy: []string{"X"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,113 @@
+package normalizejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatConfig controls how a provider decodes and encodes the JSON documents.
+// The zero value keeps the behaviors of encoding/json.
+type FormatConfig struct {
+	UseNumber   bool   // decode the JSON numbers as json.Number instead of float64 to keep their precision.
+	NullPolicy  string // how the data functions handle null, NullPolicyZero by default.
+	EmptyAsNull bool   // regard the empty string as null before the data functions.
+	Lenient     bool   // accept the JSON5 and JSONC syntax in the data and the templates updated later, see ParseLenientJSON and FormatConfigOption.
+
+	Output            string // how the result is encoded, OutputCompact by default.
+	Indent            string // the indent of OutputPretty (DefaultOutputIndent by default) and OutputOrdered (none by default).
+	DisableEscapeHTML bool   // keep <, > and & in strings instead of escaping them, always kept by OutputCanonical.
+	TrailingNewline   bool   // append a newline to the result.
+}
+
+const (
+	NullPolicyZero     = "zero"     // invoke the function with null, where the casting functions produce zero values.
+	NullPolicyPreserve = "preserve" // retain null without invoking the function.
+	NullPolicyError    = "error"    // regard null as error.
+	NullPolicyDrop     = "drop"     // remove the field (or the element of array) with null.
+)
+
+// formatDropped is the result of a dropped value, which should be removed by its parent.
+type formatDropped struct{}
+
+func isNullPolicy(policy string) bool {
+	switch policy {
+	case "", NullPolicyZero, NullPolicyPreserve, NullPolicyError, NullPolicyDrop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (config FormatConfig) check() error {
+	if !isNullPolicy(config.NullPolicy) {
+		return fmt.Errorf("unknown null policy %s", config.NullPolicy)
+	}
+	if !isOutput(config.Output) {
+		return fmt.Errorf("unknown output %s", config.Output)
+	}
+	return nil
+}
+
+// formatNullable invokes the data function with the null policy.
+func formatNullable(f FormatContextFunc, ctx *FormatContext, item interface{}, config FormatConfig) (interface{}, error) {
+	if str, ok := item.(string); ok && str == "" && config.EmptyAsNull {
+		item = nil
+	}
+
+	if item != nil {
+		return f(ctx, item)
+	}
+
+	switch config.NullPolicy {
+	case NullPolicyPreserve:
+		return nil, nil
+	case NullPolicyError:
+		return nil, fmt.Errorf("unexpected null value")
+	case NullPolicyDrop:
+		return formatDropped{}, nil
+	default:
+		return f(ctx, item)
+	}
+}
+
+func unmarshalJSON(data []byte, config FormatConfig) (interface{}, error) {
+	if config.Lenient {
+		var err error
+		if data, err = ParseLenientJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if config.UseNumber {
+		decoder.UseNumber()
+	}
+
+	var item interface{}
+	if err := decoder.Decode(&item); err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value")
+	}
+	return item, nil
+}
+
+// unmarshalTemplate decodes the template, which takes the lenient syntax with FormatConfig.Lenient.
+func unmarshalTemplate(rawTemplate []byte, config FormatConfig) (map[string]interface{}, error) {
+	if config.Lenient {
+		var err error
+		if rawTemplate, err = ParseLenientJSON(rawTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	templateMap := make(map[string]interface{})
+	if err := json.Unmarshal(rawTemplate, &templateMap); err != nil {
+		return nil, err
+	}
+	return templateMap, nil
+}
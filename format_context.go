@@ -0,0 +1,55 @@
+package normalizejson
+
+import (
+	"context"
+)
+
+// FormatContextFunc is the data function which knows where the value is, e.g. to read the currency next to the amount.
+type FormatContextFunc func(ctx *FormatContext, item interface{}) (interface{}, error)
+
+// FormatContext is the context of the value in formatting.
+//
+// Parent is the source JSON object or array, so its keys are the original ones even though Path takes the formatted
+// keys, e.g. a function at "$.payment_amount" finds its sibling "paymentCurrency" rather than "payment_currency".
+// The data provider formats the values in place, so the siblings in Parent may have been formatted.
+type FormatContext struct {
+	Context  context.Context // the context given by caller, context.Background() by default.
+	Parent   interface{}     // the JSON object or array containing the value in source, nil for the root, see below.
+	Root     interface{}     // the whole JSON document in formatting.
+	Template interface{}     // the function name, or the function leaf as a JSON object, which refers to the function.
+	Config   FormatConfig    // the config of provider.
+
+	path *formatPath
+}
+
+// Path returns the path of the value in JSON document, such as "$.data.sub_data_list[0].item1".
+func (ctx *FormatContext) Path() string {
+	return ctx.path.String()
+}
+
+// FormatDataContextOption creates a data-option with the context-aware function.
+func FormatDataContextOption(funcName string, formatFunc FormatContextFunc) FormatOption {
+	return FormatOption{
+		FunctionType:          FormatFuncFormatData,
+		FunctionName:          funcName,
+		FormatContextFunction: formatFunc,
+	}
+}
+
+// adaptFormatFunc takes FormatFunc as a context-aware function which ignores the context.
+func adaptFormatFunc(f FormatFunc) FormatContextFunc {
+	if f == nil {
+		return nil
+	}
+	return func(ctx *FormatContext, item interface{}) (interface{}, error) {
+		return f(item)
+	}
+}
+
+// takeFormatContextFunc takes the data function of option, the context-aware one is preferred.
+func takeFormatContextFunc(option FormatOption) FormatContextFunc {
+	if option.FormatContextFunction != nil {
+		return option.FormatContextFunction
+	}
+	return adaptFormatFunc(option.FormatFunction)
+}
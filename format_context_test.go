@@ -0,0 +1,155 @@
+package normalizejson
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatContextFunc(t *testing.T) {
+	var paths []string
+	roundAmount := func(ctx *FormatContext, item interface{}) (interface{}, error) {
+		paths = append(paths, ctx.Path())
+
+		amount, err := cast.ToFloat64E(item)
+		if err != nil {
+			return item, err
+		}
+
+		// the amounts in JPY have no minor unit.
+		parent, _ := ctx.Parent.(map[string]interface{})
+		if parent["currency"] == "JPY" {
+			return math.Round(amount), nil
+		}
+		return math.Round(amount*100) / 100, nil
+	}
+
+	takeSource := func(ctx *FormatContext, item interface{}) (interface{}, error) {
+		root, _ := ctx.Root.(map[string]interface{})
+		return root["source"], nil
+	}
+
+	options := []FormatOption{
+		FormatDataContextOption("round_amount", roundAmount),
+		FormatDataContextOption("take_source", takeSource),
+	}
+	template := []byte(`{"payments":[{"amount":"round_amount","source":"take_source"}]}`)
+	source := []byte(`{"source":"bank","payments":[{"amount":"12.345","currency":"USD"},{"amount":1234.5,"currency":"JPY","source":null}]}`)
+	result := []byte(`{"source":"bank","payments":[{"amount":12.35,"currency":"USD"},{"amount":1235,"currency":"JPY","source":"bank"}]}`)
+
+	for _, format := range []func(data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error){JSONSchemaFormat, JSONSchemaFormatData} {
+		paths = nil
+		formatted, err := format(source, template, options...)
+		if err != nil {
+			panic(err)
+		}
+
+		assert.Equal(t, formatJSON(result), formatJSON(formatted))
+		assert.ElementsMatch(t, []string{"$.payments[0].amount", "$.payments[1].amount"}, paths)
+	}
+}
+
+func TestFormatContextParentKeys(t *testing.T) {
+	var paths []string
+	roundAmount := func(ctx *FormatContext, item interface{}) (interface{}, error) {
+		paths = append(paths, ctx.Path())
+
+		// the parent is the source object with the original keys.
+		parent, _ := ctx.Parent.(map[string]interface{})
+		if parent["paymentCurrency"] == "JPY" {
+			return math.Round(cast.ToFloat64(item)), nil
+		}
+		return item, nil
+	}
+
+	options := []FormatOption{FormatDataContextOption("round_amount", roundAmount)}
+	for _, option := range DefaultFormatKeyOptions {
+		if option.FunctionName == FormatCamelToSnake {
+			options = append(options, option)
+		}
+	}
+	formatted, err := JSONSchemaFormat([]byte(`{"paymentAmount":1234.5,"paymentCurrency":"JPY"}`), []byte(`{"payment_amount":"round_amount"}`), options...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON([]byte(`{"payment_amount":1235,"payment_currency":"JPY"}`)), formatJSON(formatted))
+	assert.Equal(t, []string{"$.payment_amount"}, paths)
+}
+
+func TestFormatJSONSchemaContext(t *testing.T) {
+	type contextKey struct{}
+
+	var cancel context.CancelFunc
+	takeTenant := func(ctx *FormatContext, item interface{}) (interface{}, error) {
+		if cancel != nil {
+			cancel()
+		}
+		return ctx.Context.Value(contextKey{}), nil
+	}
+
+	options := []FormatOption{FormatDataContextOption("take_tenant", takeTenant)}
+	template := []byte(`{"records":[{"tenant":"take_tenant"}]}`)
+	source := []byte(`{"records":[{"tenant":null},{"tenant":null}]}`)
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "acme")
+	formatted, err := JSONSchemaFormatContext(ctx, source, template, options...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"records":[{"tenant":"acme"},{"tenant":"acme"}]}`)), formatJSON(formatted))
+
+	// the function cancels the context, and the formatting stops before the next record.
+	for _, format := range []func(ctx context.Context, data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error){JSONSchemaFormatContext, JSONSchemaFormatDataContext} {
+		ctx, cancel = context.WithCancel(ctx)
+		_, err = format(ctx, source, template, options...)
+		assert.True(t, errors.Is(err, context.Canceled))
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = JSONSchemaFormatKeyContext(ctx, []byte(`{"userId":1}`), DefaultFormatKeyOptions...)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	provider, err := NewFormatProvider(template, options...)
+	if err != nil {
+		panic(err)
+	}
+	_, err = provider.(ContextFormatProvider).FormatJSONSchemaContext(ctx, source)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// the provider without context is checked before the normalization.
+	_, err = formatJSONSchemaContext(ctx, plainProvider{provider}, source)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	formatted, err = formatJSONSchemaContext(context.Background(), plainProvider{provider}, []byte(`{"records":[]}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"records":[]}`, string(formatted))
+}
+
+// plainProvider implements only FormatProvider, as the providers outside this package may do.
+type plainProvider struct {
+	provider FormatProvider
+}
+
+func (p plainProvider) AddOptions(options ...FormatOption) {
+	p.provider.AddOptions(options...)
+}
+
+func (p plainProvider) UpdateTemplate(rawTemplate []byte) error {
+	return p.provider.UpdateTemplate(rawTemplate)
+}
+
+func (p plainProvider) FormatJSONSchema(data []byte) ([]byte, error) {
+	return p.provider.FormatJSONSchema(data)
+}
+
+func (p plainProvider) Reset() {
+	p.provider.Reset()
+}
@@ -0,0 +1,397 @@
+package normalizejson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CSVConfig configures the reading and writing of CSV, the zero value of each field takes the default one.
+type CSVConfig struct {
+	Comma       rune        // the separator of fields, ',' by default, e.g. '\t' for TSV.
+	Header      []string    // the names of columns, which are taken from the first row in reading without it.
+	EmptyAsNull bool        // take the empty cells as null instead of "" in reading.
+	Array       bool        // write the rows as a JSON array instead of NDJSON.
+	Batch       BatchConfig // the batch processing of rows, whose BatchLineError.Line is the line of row in CSV.
+}
+
+func (config CSVConfig) comma() rune {
+	if config.Comma == 0 {
+		return ','
+	}
+	return config.Comma
+}
+
+// FormatCSV reads the rows of CSV from r as JSON objects keyed by the header, normalizes them with provider as
+// FormatNDJSON does, and writes them to w as NDJSON or a JSON array. The key options of template apply to the header,
+// e.g. {"__keys":"camel_to_snake","user_id":"to_int64"}, and the cells are taken as strings.
+// A leading UTF-8 BOM is skipped, the duplicate names in header are errors, and the malformed rows are reported as
+// the failed lines without stopping the batch.
+func FormatCSV(ctx context.Context, provider FormatProvider, r io.Reader, w io.Writer, config CSVConfig) (BatchResult, error) {
+	reader := csv.NewReader(skipBOM(r))
+	reader.Comma = config.comma()
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header := config.Header
+	if len(header) == 0 {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("missing header")
+			}
+			return BatchResult{}, fmt.Errorf("read CSV failed: %s", err)
+		}
+		header = append([]string(nil), record...)
+	}
+	seen := make(map[string]bool, len(header))
+	for _, name := range header {
+		if seen[name] {
+			return BatchResult{}, fmt.Errorf("read CSV failed: duplicate column %s in header", name)
+		}
+		seen[name] = true
+	}
+
+	// the rows are written to the pipe as NDJSON, and the numbers of lines in NDJSON are mapped to the ones in CSV.
+	// The malformed rows are written as the placeholders, which fail with their errors in csvRowProvider.
+	var lock sync.Mutex
+	var csvLines []int
+	var rowErrors []error
+	csvLine := func(line int) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return csvLines[line-1]
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		writer := bufio.NewWriter(pw)
+		for {
+			var data []byte
+			var line int
+			record, err := reader.Read()
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				lock.Lock()
+				data = []byte(csvErrorMark + strconv.Itoa(len(rowErrors)))
+				rowErrors = append(rowErrors, err)
+				lock.Unlock()
+				line = parseErr.StartLine
+			} else if err != nil {
+				// flush the rows read before closing the pipe.
+				if flushErr := writer.Flush(); flushErr != nil {
+					err = flushErr
+				} else if !errors.Is(err, io.EOF) {
+					err = fmt.Errorf("read CSV failed: %s", err)
+				}
+				pw.CloseWithError(err)
+				return
+			} else {
+				line, _ = reader.FieldPos(0)
+				if data, err = json.Marshal(csvRecordValue(header, record, config.EmptyAsNull)); err != nil {
+					writer.Flush()
+					pw.CloseWithError(fmt.Errorf("read CSV failed: line %d: %s", line, err))
+					return
+				}
+			}
+
+			lock.Lock()
+			csvLines = append(csvLines, line)
+			lock.Unlock()
+			if _, err = writer.Write(append(data, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+
+	// collect the errors here, since BatchLineError.Line is mapped in OnError.
+	var lineErrors []*BatchLineError
+	batch := config.Batch
+	onError := batch.OnError
+	batch.OnError = func(err *BatchLineError) {
+		err.Line = csvLine(err.Line)
+		if onError != nil {
+			onError(err)
+		} else {
+			lineErrors = append(lineErrors, err)
+		}
+	}
+
+	// the placeholders of malformed rows are never written, even with BatchConfig.KeepFailed.
+	var out io.Writer = csvRowWriter{w: w}
+	var arrayWriter *jsonArrayWriter
+	if config.Array {
+		arrayWriter = &jsonArrayWriter{w: w}
+		out = csvRowWriter{w: arrayWriter}
+	}
+
+	rowProvider := csvRowProvider{provider: provider, rowError: func(index int) error {
+		lock.Lock()
+		defer lock.Unlock()
+		return rowErrors[index]
+	}}
+	result, err := FormatNDJSON(ctx, rowProvider, pr, out, batch)
+	// stop the reading of CSV if the batch stops early.
+	pr.Close()
+	result.Errors = lineErrors
+	if err != nil {
+		return result, err
+	}
+
+	if arrayWriter != nil {
+		if err = arrayWriter.Close(); err != nil {
+			return result, fmt.Errorf("write JSON failed: %s", err)
+		}
+	}
+	return result, nil
+}
+
+// csvErrorMark starts the NDJSON placeholders of the malformed rows, which is never valid JSON.
+const csvErrorMark = "\x00csv_error:"
+
+// utf8BOM is the byte order mark which some tools write at the start of CSV.
+var utf8BOM = []byte("\ufeff")
+
+func skipBOM(r io.Reader) io.Reader {
+	reader := bufio.NewReader(r)
+	if prefix, err := reader.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		_, _ = reader.Discard(len(utf8BOM))
+	}
+	return reader
+}
+
+// csvRowProvider fails the placeholders of malformed rows with their errors, and normalizes the others with provider.
+type csvRowProvider struct {
+	provider FormatProvider
+	rowError func(index int) error
+}
+
+func (p csvRowProvider) AddOptions(options ...FormatOption) {
+	p.provider.AddOptions(options...)
+}
+
+func (p csvRowProvider) UpdateTemplate(rawTemplate []byte) error {
+	return p.provider.UpdateTemplate(rawTemplate)
+}
+
+func (p csvRowProvider) FormatJSONSchema(data []byte) ([]byte, error) {
+	return p.FormatJSONSchemaContext(context.Background(), data)
+}
+
+func (p csvRowProvider) FormatJSONSchemaContext(ctx context.Context, data []byte) ([]byte, error) {
+	if index, ok := csvErrorIndex(data); ok {
+		return nil, p.rowError(index)
+	}
+	return formatJSONSchemaContext(ctx, p.provider, data)
+}
+
+func (p csvRowProvider) Reset() {
+	p.provider.Reset()
+}
+
+func csvErrorIndex(data []byte) (int, bool) {
+	if !bytes.HasPrefix(data, []byte(csvErrorMark)) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(string(data[len(csvErrorMark):]))
+	return index, err == nil
+}
+
+// csvRowWriter drops the placeholders of malformed rows, each Write takes a whole line as FormatNDJSON does.
+type csvRowWriter struct {
+	w io.Writer
+}
+
+func (w csvRowWriter) Write(p []byte) (int, error) {
+	if bytes.HasPrefix(p, []byte(csvErrorMark)) {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+func csvRecordValue(header []string, record []string, emptyAsNull bool) map[string]interface{} {
+	value := make(map[string]interface{}, len(header))
+	for index, name := range header {
+		if index >= len(record) || (emptyAsNull && record[index] == "") {
+			value[name] = nil
+			continue
+		}
+		value[name] = record[index]
+	}
+	// the extra cells are kept by their indexes.
+	for index := len(header); index < len(record); index++ {
+		value[strconv.Itoa(index)] = record[index]
+	}
+	return value
+}
+
+// jsonArrayWriter writes the lines of NDJSON as a JSON array, each Write takes a whole line as FormatNDJSON does.
+type jsonArrayWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (w *jsonArrayWriter) Write(p []byte) (int, error) {
+	prefix := []byte(",\n")
+	if w.count == 0 {
+		prefix = []byte("[\n")
+	}
+	if _, err := w.w.Write(append(prefix, bytes.TrimSuffix(p, []byte("\n"))...)); err != nil {
+		return 0, err
+	}
+	w.count++
+	return len(p), nil
+}
+
+func (w *jsonArrayWriter) Close() error {
+	suffix := "\n]\n"
+	if w.count == 0 {
+		suffix = "[]\n"
+	}
+	_, err := io.WriteString(w.w, suffix)
+	return err
+}
+
+// JSONToCSV reads a JSON array or NDJSON of objects from r, and writes them to w as CSV, in which the nested values
+// are flattened with dotted column names, e.g. {"user":{"tags":["a"]}} as the column "user.tags.0".
+// The columns are config.Header if given, or all the columns in the order of their first appearance, and the nulls
+// are written as empty cells.
+func JSONToCSV(r io.Reader, w io.Writer, config CSVConfig) error {
+	rows, err := readJSONRows(r)
+	if err != nil {
+		return err
+	}
+
+	header := config.Header
+	flattenedRows := make([]map[string]string, 0, len(rows))
+	seen := make(map[string]bool)
+	for index, row := range rows {
+		itemMap, ok := row.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("convert JSON to CSV failed: row %d should be an object, but got %T", index+1, row)
+		}
+
+		flattened := make(map[string]string)
+		flattenJSONValue("", itemMap, flattened)
+		flattenedRows = append(flattenedRows, flattened)
+
+		if len(config.Header) > 0 {
+			continue
+		}
+		for _, name := range sortedColumns(flattened) {
+			if !seen[name] {
+				seen[name] = true
+				header = append(header, name)
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = config.comma()
+	if err = writer.Write(header); err != nil {
+		return fmt.Errorf("write CSV failed: %s", err)
+	}
+	record := make([]string, len(header))
+	for _, flattened := range flattenedRows {
+		for index, name := range header {
+			record[index] = flattened[name]
+		}
+		if err = writer.Write(record); err != nil {
+			return fmt.Errorf("write CSV failed: %s", err)
+		}
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return fmt.Errorf("write CSV failed: %s", err)
+	}
+	return nil
+}
+
+// readJSONRows reads the JSON array, or the NDJSON records.
+func readJSONRows(r io.Reader) ([]interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var rows []interface{}
+	for {
+		var row interface{}
+		if err := decoder.Decode(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+		}
+		if list, ok := row.([]interface{}); ok && rows == nil {
+			rows = list
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// flattenJSONValue flattens the value into the cells with dotted names, the empty objects and arrays are kept as JSON.
+func flattenJSONValue(name string, value interface{}, cells map[string]string) {
+	join := func(key string) string {
+		if name == "" {
+			return key
+		}
+		return name + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && name != "" {
+			cells[name] = "{}"
+		}
+		for key, item := range v {
+			flattenJSONValue(join(key), item, cells)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			cells[name] = "[]"
+		}
+		for index, item := range v {
+			flattenJSONValue(join(strconv.Itoa(index)), item, cells)
+		}
+	case nil:
+		cells[name] = ""
+	case string:
+		cells[name] = v
+	default:
+		cells[name] = fmt.Sprint(v)
+	}
+}
+
+// sortedColumns sorts the columns by their names, in which the indexes of arrays are compared as numbers.
+func sortedColumns(cells map[string]string) []string {
+	names := make([]string, 0, len(cells))
+	for name := range cells {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := strings.Split(names[i], "."), strings.Split(names[j], ".")
+		for index := 0; index < len(a) && index < len(b); index++ {
+			if a[index] == b[index] {
+				continue
+			}
+			x, errX := strconv.Atoi(a[index])
+			y, errY := strconv.Atoi(b[index])
+			if errX == nil && errY == nil {
+				return x < y
+			}
+			return a[index] < b[index]
+		}
+		return len(a) < len(b)
+	})
+	return names
+}
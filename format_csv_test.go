@@ -0,0 +1,129 @@
+package normalizejson
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatCSV(t *testing.T) {
+	dir := "format_csv"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.csv")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	var buf bytes.Buffer
+	batchResult, err := FormatCSV(context.Background(), provider, bytes.NewReader(source), &buf, CSVConfig{EmptyAsNull: true, Array: true})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, 2, batchResult.Lines)
+	assert.Equal(t, formatJSON(result), formatJSON(buf.Bytes()))
+}
+
+func TestFormatCSVErrors(t *testing.T) {
+	provider, err := NewFormatProvider([]byte(`{"id":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	// the lines of errors are the ones in TSV, and the failed rows are skipped.
+	var buf bytes.Buffer
+	source := "id\tname\n1\t\"a\nb\"\nx\tc\n3\td\n"
+	batchResult, err := FormatCSV(context.Background(), provider, strings.NewReader(source), &buf, CSVConfig{Comma: '\t'})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, 3, batchResult.Lines)
+	assert.Equal(t, 1, batchResult.Failed)
+	assert.Equal(t, 4, batchResult.Errors[0].Line)
+	assert.Equal(t, "$.id", batchResult.Errors[0].Path)
+	assert.Equal(t, "{\"id\":1,\"name\":\"a\\nb\"}\n{\"id\":3,\"name\":\"d\"}\n", buf.String())
+
+	// the header is given.
+	buf.Reset()
+	_, err = FormatCSV(context.Background(), provider, strings.NewReader("1,a,extra\n"), &buf, CSVConfig{Header: []string{"id", "name"}})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"id":1,"name":"a","2":"extra"}`)), formatJSON(buf.Bytes()))
+
+	_, err = FormatCSV(context.Background(), provider, strings.NewReader(""), &buf, CSVConfig{})
+	assert.NotNil(t, err)
+}
+
+func TestFormatCSVMalformed(t *testing.T) {
+	provider, err := NewFormatProvider([]byte(`{"id":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	// the malformed row fails alone, and the rows around it are written.
+	for _, keepFailed := range []bool{false, true} {
+		var buf bytes.Buffer
+		source := "id,name\n1,a\n2,x\"y\n3,c"
+		batchResult, err := FormatCSV(context.Background(), provider, strings.NewReader(source), &buf, CSVConfig{Batch: BatchConfig{KeepFailed: keepFailed}})
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, 3, batchResult.Lines)
+		assert.Equal(t, 1, batchResult.Failed)
+		assert.Equal(t, 3, batchResult.Errors[0].Line)
+		var parseErr *csv.ParseError
+		assert.True(t, errors.As(batchResult.Errors[0], &parseErr))
+		assert.Equal(t, "{\"id\":1,\"name\":\"a\"}\n{\"id\":3,\"name\":\"c\"}\n", buf.String())
+	}
+
+	// the BOM is skipped, and the duplicate names in header are errors.
+	var buf bytes.Buffer
+	_, err = FormatCSV(context.Background(), provider, strings.NewReader("\ufeffid,name\n1,a\n"), &buf, CSVConfig{})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "{\"id\":1,\"name\":\"a\"}\n", buf.String())
+
+	_, err = FormatCSV(context.Background(), provider, strings.NewReader("id,id\n1,2\n"), &buf, CSVConfig{})
+	assert.NotNil(t, err)
+
+	_, err = FormatCSV(context.Background(), provider, strings.NewReader("1,2\n"), &buf, CSVConfig{Header: []string{"id", "id"}})
+	assert.NotNil(t, err)
+}
+
+func TestJSONToCSV(t *testing.T) {
+	var buf bytes.Buffer
+	source := `[{"id":1,"user":{"name":"a, b","tags":["x","y"]},"none":null},{"id":2,"user":{"tags":[]},"extra":true}]`
+	if err := JSONToCSV(strings.NewReader(source), &buf, CSVConfig{}); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "id,none,user.name,user.tags.0,user.tags.1,extra,user.tags\n1,,\"a, b\",x,y,,\n2,,,,,true,[]\n", buf.String())
+
+	// NDJSON with the given header, as TSV.
+	buf.Reset()
+	if err := JSONToCSV(strings.NewReader("{\"id\":1,\"n\":1.50}\n{\"id\":2}\n"), &buf, CSVConfig{Comma: '\t', Header: []string{"n", "id"}}); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "n\tid\n1.50\t1\n\t2\n", buf.String())
+
+	assert.NotNil(t, JSONToCSV(strings.NewReader(`[1]`), &buf, CSVConfig{}))
+}
@@ -1,17 +1,21 @@
 package normalizejson
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"strings"
 )
 
 func JSONSchemaFormatData(data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error) {
+	return JSONSchemaFormatDataContext(context.Background(), data, rawTemplate, options...)
+}
+
+func JSONSchemaFormatDataContext(ctx context.Context, data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error) {
 	fii, err := newFormatDataImpl(rawTemplate, options...)
 	if err != nil {
 		return nil, fmt.Errorf("format JSON data failed: %s", err)
 	}
-	return fii.formatJSONSchema(data)
+	return fii.formatJSONSchema(ctx, data)
 }
 
 func DefaultJSONSchemaFormatData(data []byte, rawTemplate []byte) ([]byte, error) {
@@ -19,8 +23,11 @@ func DefaultJSONSchemaFormatData(data []byte, rawTemplate []byte) ([]byte, error
 }
 
 type formatDataImpl struct {
-	functionMap map[string]FormatFunc
+	functionMap map[string]FormatContextFunc
+	builderMap  map[string]FormatFuncBuilder
 	templateMap map[string]interface{}
+	templateErr error
+	config      FormatConfig
 }
 
 const (
@@ -42,60 +49,101 @@ func newFormatDataImpl(rawTemplate []byte, options ...FormatOption) (*formatData
 }
 
 func (fdi *formatDataImpl) reset() {
-	fdi.functionMap = make(map[string]FormatFunc)
+	fdi.functionMap = make(map[string]FormatContextFunc)
+	fdi.builderMap = make(map[string]FormatFuncBuilder)
 	fdi.templateMap = make(map[string]interface{})
+	fdi.templateErr = nil
+	fdi.config = FormatConfig{}
 }
 
 func (fdi *formatDataImpl) updateTemplate(rawTemplate []byte) error {
-	templateMap := make(map[string]interface{})
-	if err := json.Unmarshal(rawTemplate, &templateMap); err != nil {
+	templateMap, err := unmarshalTemplate(rawTemplate, fdi.config)
+	if err != nil {
+		return err
+	}
+	if _, err := compileTemplate(templateMap, fdi.functionMap, fdi.builderMap); err != nil {
 		return err
 	}
 	fdi.templateMap = templateMap
+	fdi.templateErr = nil
 	return nil
 }
 
 func (fdi *formatDataImpl) addOptions(options ...FormatOption) {
 	if fdi.functionMap == nil {
-		fdi.functionMap = make(map[string]FormatFunc)
+		fdi.functionMap = make(map[string]FormatContextFunc)
+	}
+
+	if fdi.builderMap == nil {
+		fdi.builderMap = make(map[string]FormatFuncBuilder)
 	}
 
 	for _, option := range options {
-		fdi.functionMap[option.FunctionName] = option.FormatFunction
+		if option.FunctionType == FormatFuncFormatConfig {
+			fdi.config = option.Config
+			continue
+		}
+		if f := takeFormatContextFunc(option); f != nil {
+			fdi.functionMap[option.FunctionName] = f
+		}
+		if option.FormatBuilder != nil {
+			fdi.builderMap[option.FunctionName] = option.FormatBuilder
+		}
 	}
+
+	// rebuild the function leaves in template with the new options.
+	_, fdi.templateErr = compileTemplate(fdi.templateMap, fdi.functionMap, fdi.builderMap)
 }
 
-func (fdi *formatDataImpl) formatJSONSchema(data []byte) ([]byte, error) {
-	if len(fdi.functionMap) == 0 {
+func (fdi *formatDataImpl) formatJSONSchema(ctx context.Context, data []byte) ([]byte, error) {
+	if fdi.templateErr != nil {
+		return nil, fmt.Errorf("compile template failed: %s", fdi.templateErr)
+	}
+
+	if err := fdi.config.check(); err != nil {
+		return nil, err
+	}
+
+	if len(fdi.functionMap) == 0 && len(fdi.builderMap) == 0 && len(fdi.templateMap) == 0 && fdi.config.keepsData() {
 		return data, nil
 	}
 
-	var item interface{}
-	if err := json.Unmarshal(data, &item); err != nil {
-		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	item, err := unmarshalJSON(data, fdi.config)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %w", err)
 	}
 
-	formattedItem, err := fdi.formatItem(item)
+	// the "__redact" directive of root template applies to the whole JSON document.
+	formattedItem, err := fdi.formatItem(newFormatScope(ctx, item).withRedact(fdi.templateMap), item)
 	if err != nil {
-		return nil, fmt.Errorf("format JSON data failed: %s", err)
+		return nil, fmt.Errorf("format JSON data failed: %w", err)
 	}
-	return json.Marshal(formattedItem)
+	return marshalOutput(formattedItem, data, fdi.config)
 }
 
-func (fdi *formatDataImpl) formatItem(item interface{}) (interface{}, error) {
+func (fdi *formatDataImpl) formatItem(scope formatScope, item interface{}) (interface{}, error) {
+	// stop formatting once the context is cancelled.
+	if err := scope.ctx.Err(); err != nil {
+		return item, err
+	}
+
 	switch v := item.(type) {
 	case []interface{}:
-		return fdi.formatItemList(v)
+		return fdi.formatItemList(scope, v)
 	case map[string]interface{}:
-		return fdi.formatItemMap(v)
+		return fdi.formatItemMap(scope, v)
 	default:
 		return item, nil
 	}
 }
 
-func (fdi *formatDataImpl) formatItemList(itemList []interface{}) ([]interface{}, error) {
+func (fdi *formatDataImpl) formatItemList(scope formatScope, itemList []interface{}) ([]interface{}, error) {
 	for index, item := range itemList {
-		formattedItem, err := fdi.formatItem(item)
+		formattedItem, err := fdi.formatItem(scope.element(index, itemList), item)
 		if err != nil {
 			return itemList, err
 		}
@@ -104,20 +152,29 @@ func (fdi *formatDataImpl) formatItemList(itemList []interface{}) ([]interface{}
 	return itemList, nil
 }
 
-func (fdi *formatDataImpl) formatItemMap(itemMap map[string]interface{}) (map[string]interface{}, error) {
+func (fdi *formatDataImpl) formatItemMap(scope formatScope, itemMap map[string]interface{}) (map[string]interface{}, error) {
 	for key, item := range itemMap {
+		if scope.needRedact(key) {
+			itemMap[key] = RedactedValue
+			continue
+		}
+
 		template, ok := fdi.templateMap[key]
 		if !ok {
-			formattedItem, err := fdi.formatItem(item)
+			formattedItem, err := fdi.formatItem(scope.child(key, itemMap), item)
 			if err != nil {
 				return itemMap, err
 			}
 			itemMap[key] = formattedItem
 		} else {
-			formattedItem, err := fdi.formatItemByTemplate(item, template)
+			formattedItem, err := fdi.formatItemByTemplate(scope.child(key, itemMap), item, template)
 			if err != nil {
 				return itemMap, err
 			}
+			if _, ok := formattedItem.(formatDropped); ok {
+				delete(itemMap, key)
+				continue
+			}
 			itemMap[key] = formattedItem
 		}
 	}
@@ -125,71 +182,120 @@ func (fdi *formatDataImpl) formatItemMap(itemMap map[string]interface{}) (map[st
 	return itemMap, nil
 }
 
-func (fdi *formatDataImpl) formatItemByTemplate(item interface{}, template interface{}) (interface{}, error) {
+func (fdi *formatDataImpl) formatItemByTemplate(scope formatScope, item interface{}, template interface{}) (interface{}, error) {
+	if err := scope.ctx.Err(); err != nil {
+		return item, err
+	}
+
 	switch v := template.(type) {
+	case *formatFuncNode:
+		formattedItem, err := v.format(scope.formatContext(v, fdi.config), item, fdi.config)
+		return formattedItem, wrapFormatError(scope.path, err)
+	case *formatEmbeddedNode:
+		return fdi.formatEmbeddedItem(scope, item, v)
 	case string:
 		if needTemplate(v) {
-			return fdi.takeTemplate(item, v)
+			return fdi.takeTemplate(scope, item, v)
 		}
 		if f, ok := fdi.functionMap[v]; !ok {
 			return item, nil
 		} else {
-			return f(item)
+			formattedItem, err := formatNullable(f, scope.formatContext(v, fdi.config), item, fdi.config)
+			return formattedItem, wrapFormatError(scope.path, err)
 		}
 	case []interface{}:
 		itemList, ok := item.([]interface{})
 		if !ok {
 			return item, nil
 		}
-		return fdi.formatItemListByTemplate(itemList, v)
+		return fdi.formatItemListByTemplate(scope, itemList, v)
 	case map[string]interface{}:
 		itemMap, ok := item.(map[string]interface{})
 		if !ok {
 			return item, nil
 		}
-		return fdi.formatItemMapByTemplate(itemMap, v)
+		return fdi.formatItemMapByTemplate(scope, itemMap, v)
 	default:
 		return item, nil
 	}
 }
 
-func (fdi *formatDataImpl) formatItemListByTemplate(itemList []interface{}, templateList []interface{}) ([]interface{}, error) {
+// formatEmbeddedItem formats the JSON value serialized as a string with the nested template, or serializes the
+// formatted value to a string.
+func (fdi *formatDataImpl) formatEmbeddedItem(scope formatScope, item interface{}, node *formatEmbeddedNode) (interface{}, error) {
+	if node.encode {
+		formattedItem, err := fdi.formatItemByTemplate(scope, item, node.template)
+		if err != nil {
+			return item, err
+		}
+		formattedItem, err = node.encodeItem(formattedItem)
+		return formattedItem, wrapFormatError(scope.path, err)
+	}
+
+	decodedItem, err := node.decodeItem(item, fdi.config)
+	if err != nil {
+		return item, wrapFormatError(scope.path, err)
+	}
+	return fdi.formatItemByTemplate(scope, decodedItem, node.template)
+}
+
+func (fdi *formatDataImpl) formatItemListByTemplate(scope formatScope, itemList []interface{}, templateList []interface{}) ([]interface{}, error) {
 	if len(templateList) == 0 {
 		return itemList, nil
 	}
+	formattedList := itemList[:0]
 	for index, item := range itemList {
-		formattedItem, err := fdi.formatItemByTemplate(item, templateList[0])
+		formattedItem, err := fdi.formatItemByTemplate(scope.element(index, itemList), item, templateList[0])
 		if err != nil {
 			return itemList, err
 		}
-		itemList[index] = formattedItem
+		if _, ok := formattedItem.(formatDropped); ok {
+			continue
+		}
+		formattedList = append(formattedList, formattedItem)
 	}
-	return itemList, nil
+	return formattedList, nil
 }
 
-func (fdi *formatDataImpl) formatItemMapByTemplate(itemMap map[string]interface{}, templateMap map[string]interface{}) (map[string]interface{}, error) {
+func (fdi *formatDataImpl) formatItemMapByTemplate(scope formatScope, itemMap map[string]interface{}, templateMap map[string]interface{}) (map[string]interface{}, error) {
+	// the "__redact" directive applies to all the values of this JSON object, including the ones out of template.
+	scope = scope.withRedact(templateMap)
+	if len(scope.redactPatterns) > 0 {
+		for key, item := range itemMap {
+			if scope.needRedact(key) {
+				itemMap[key] = RedactedValue
+			} else if _, ok := templateMap[key]; !ok {
+				itemMap[key] = scope.redactItem(item)
+			}
+		}
+	}
+
 	for key, template := range templateMap {
 		item, exist := itemMap[key]
-		if !exist {
+		if !exist || scope.needRedact(key) {
 			continue
 		}
 
-		formattedItem, err := fdi.formatItemByTemplate(item, template)
+		formattedItem, err := fdi.formatItemByTemplate(scope.child(key, itemMap), item, template)
 		if err != nil {
 			return itemMap, err
 		}
+		if _, ok := formattedItem.(formatDropped); ok {
+			delete(itemMap, key)
+			continue
+		}
 		itemMap[key] = formattedItem
 	}
 	return itemMap, nil
 }
 
-func (fdi *formatDataImpl) takeTemplate(item interface{}, expr string) (interface{}, error) {
+func (fdi *formatDataImpl) takeTemplate(scope formatScope, item interface{}, expr string) (interface{}, error) {
 	templateKey := strings.TrimPrefix(expr, formatDataTemplatePrefix)
 	template, ok := fdi.templateMap[templateKey]
 	if !ok {
 		return item, nil
 	}
-	return fdi.formatItemByTemplate(item, template)
+	return fdi.formatItemByTemplate(scope, item, template)
 }
 
 func needTemplate(function string) bool {
@@ -0,0 +1,314 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/Grivn/normalizejson/regex"
+)
+
+const (
+	FormatToDecimal = "to_decimal"
+	FormatToBigInt  = "to_big_int"
+	FormatToInt     = "to_int"
+)
+
+// DecimalFormatDataOptions are the data-options to normalize numbers without losing precision,
+// which emit json.Number to keep the exact digits in JSON. Use them with FormatConfig.UseNumber,
+// otherwise the JSON numbers have been decoded as float64 before formatting.
+//
+// The parameters in function leaf of template:
+//
+//   - "to_decimal": "scale" is the count of digits after the decimal point, the digits are retained by default;
+//     "rounding" is the mode to round to the scale, one of "half_up" (default), "half_down", "half_even",
+//     "up", "down", "ceiling" and "floor"; "output" is "number" (default) or "string".
+//   - "to_big_int": "rounding" is the mode to round the fraction, a fraction is regarded as error by default.
+//   - "to_int": "bits" is the size of integer, one of 8, 16, 32 and 64 (default); "rounding" is the same as
+//     "to_big_int". An overflowed value is regarded as error rather than wrapped.
+//
+// E.g. {"__function":"to_decimal","scale":2,"rounding":"half_even"}.
+var DecimalFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatToDecimal, BuildFormatDataToDecimal),
+	FormatDataBuilderOption(FormatToBigInt, BuildFormatDataToBigInt),
+	FormatDataBuilderOption(FormatToInt, BuildFormatDataToInt),
+}
+
+const (
+	decimalRoundingHalfUp   = "half_up"
+	decimalRoundingHalfDown = "half_down"
+	decimalRoundingHalfEven = "half_even"
+	decimalRoundingUp       = "up"
+	decimalRoundingDown     = "down"
+	decimalRoundingCeiling  = "ceiling"
+	decimalRoundingFloor    = "floor"
+
+	decimalOutputNumber = "number"
+	decimalOutputString = "string"
+
+	// maxDecimalScale limits the digits after (or before) the decimal point, so that a short input with a large
+	// exponent, e.g. "1e100000000", cannot build a huge number.
+	maxDecimalScale = 10000
+)
+
+func BuildFormatDataToDecimal(params map[string]interface{}) (FormatFunc, error) {
+	scale, err := paramInt(params, "scale", -1)
+	if err != nil {
+		return nil, err
+	}
+	if scale > maxDecimalScale {
+		return nil, fmt.Errorf("illegal parameter scale: %d exceeds %d", scale, maxDecimalScale)
+	}
+
+	rounding, err := paramDecimalRounding(params, decimalRoundingHalfUp)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := paramString(params, "output", decimalOutputNumber)
+	if err != nil {
+		return nil, err
+	}
+	if output != decimalOutputNumber && output != decimalOutputString {
+		return nil, fmt.Errorf("unknown output %s", output)
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		if item == nil {
+			return nil, nil
+		}
+
+		d, err := parseDecimal(item)
+		if err != nil {
+			return item, err
+		}
+
+		if scale >= 0 {
+			d = d.round(scale, rounding)
+		} else {
+			d = d.trim()
+		}
+
+		if output == decimalOutputString {
+			return d.String(), nil
+		}
+		return json.Number(d.String()), nil
+	}, nil
+}
+
+func BuildFormatDataToBigInt(params map[string]interface{}) (FormatFunc, error) {
+	rounding, err := paramDecimalRounding(params, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		if item == nil {
+			return nil, nil
+		}
+
+		i, err := parseDecimalInteger(item, rounding)
+		if err != nil {
+			return item, err
+		}
+		return json.Number(i.String()), nil
+	}, nil
+}
+
+func BuildFormatDataToInt(params map[string]interface{}) (FormatFunc, error) {
+	bits, err := paramInt(params, "bits", 64)
+	if err != nil {
+		return nil, err
+	}
+	if bits != 8 && bits != 16 && bits != 32 && bits != 64 {
+		return nil, fmt.Errorf("unsupported bits %d", bits)
+	}
+
+	rounding, err := paramDecimalRounding(params, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		if item == nil {
+			return nil, nil
+		}
+
+		i, err := parseDecimalInteger(item, rounding)
+		if err != nil {
+			return item, err
+		}
+
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+		if i.Cmp(limit) >= 0 || i.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return item, fmt.Errorf("%s overflows int%d", i.String(), bits)
+		}
+		return i.Int64(), nil
+	}, nil
+}
+
+func paramDecimalRounding(params map[string]interface{}, defaultValue string) (string, error) {
+	rounding, err := paramString(params, "rounding", defaultValue)
+	if err != nil {
+		return rounding, err
+	}
+
+	switch rounding {
+	case "", decimalRoundingHalfUp, decimalRoundingHalfDown, decimalRoundingHalfEven,
+		decimalRoundingUp, decimalRoundingDown, decimalRoundingCeiling, decimalRoundingFloor:
+		return rounding, nil
+	default:
+		return rounding, fmt.Errorf("unknown rounding %s", rounding)
+	}
+}
+
+// parseDecimalInteger parses an integer, the fraction is rounded with the rounding mode, or regarded as error
+// when no rounding mode is given.
+func parseDecimalInteger(item interface{}, rounding string) (*big.Int, error) {
+	d, err := parseDecimal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	if rounding != "" {
+		return d.round(0, rounding).unscaled, nil
+	}
+
+	d = d.trim()
+	if d.scale > 0 {
+		return nil, fmt.Errorf("unable to cast %#v of type %T to integer without rounding", item, item)
+	}
+	return d.round(0, decimalRoundingDown).unscaled, nil
+}
+
+// decimal is the number of unscaled * 10^(-scale).
+type decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+func parseDecimal(item interface{}) (decimal, error) {
+	var str string
+	switch v := item.(type) {
+	case json.Number:
+		str = v.String()
+	case string:
+		str = strings.TrimSpace(v)
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		str = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		str = fmt.Sprint(v)
+	case *big.Int:
+		return decimal{unscaled: new(big.Int).Set(v)}, nil
+	default:
+		return decimal{}, fmt.Errorf("unable to cast %#v of type %T to decimal", item, item)
+	}
+
+	match := regex.Decimal.FindStringSubmatch(str)
+	if match == nil || match[2]+match[3] == "" {
+		return decimal{}, fmt.Errorf("unable to cast %#v of type %T to decimal", item, item)
+	}
+
+	unscaled, _ := new(big.Int).SetString(match[2]+match[3], 10)
+	if match[1] == "-" {
+		unscaled.Neg(unscaled)
+	}
+
+	d := decimal{unscaled: unscaled, scale: len(match[3])}
+	if match[4] != "" {
+		exponent, err := strconv.ParseInt(match[4], 10, 32)
+		if err != nil {
+			return decimal{}, fmt.Errorf("unable to cast %#v of type %T to decimal", item, item)
+		}
+		d.scale -= int(exponent)
+	}
+	if d.scale > maxDecimalScale || d.scale < -maxDecimalScale {
+		return decimal{}, fmt.Errorf("the exponent of %#v is out of range", item)
+	}
+
+	// the negative scale is expanded to keep a plain decimal string.
+	if d.scale < 0 {
+		d = d.round(0, decimalRoundingDown)
+	}
+	return d, nil
+}
+
+// round returns the decimal with the given scale, the dropped digits are rounded with the mode.
+func (d decimal) round(scale int, rounding string) decimal {
+	if d.scale <= scale {
+		unscaled := new(big.Int).Mul(d.unscaled, pow10(scale-d.scale))
+		return decimal{unscaled: unscaled, scale: scale}
+	}
+
+	divisor := pow10(d.scale - scale)
+	quotient, remainder := new(big.Int).QuoRem(d.unscaled, divisor, new(big.Int))
+	if remainder.Sign() == 0 {
+		return decimal{unscaled: quotient, scale: scale}
+	}
+
+	// compare the dropped digits with the half.
+	half := new(big.Int).Abs(remainder)
+	half.Mul(half, big.NewInt(2))
+	cmpHalf := half.Cmp(divisor)
+
+	sign := d.unscaled.Sign()
+	var awayFromZero bool
+	switch rounding {
+	case decimalRoundingUp:
+		awayFromZero = true
+	case decimalRoundingDown:
+		awayFromZero = false
+	case decimalRoundingCeiling:
+		awayFromZero = sign > 0
+	case decimalRoundingFloor:
+		awayFromZero = sign < 0
+	case decimalRoundingHalfDown:
+		awayFromZero = cmpHalf > 0
+	case decimalRoundingHalfEven:
+		awayFromZero = cmpHalf > 0 || (cmpHalf == 0 && quotient.Bit(0) == 1)
+	default:
+		awayFromZero = cmpHalf >= 0
+	}
+
+	if awayFromZero {
+		quotient.Add(quotient, big.NewInt(int64(sign)))
+	}
+	return decimal{unscaled: quotient, scale: scale}
+}
+
+// trim removes the trailing zeros after the decimal point.
+func (d decimal) trim() decimal {
+	unscaled, scale := new(big.Int).Set(d.unscaled), d.scale
+	ten, remainder := big.NewInt(10), new(big.Int)
+	for scale > 0 {
+		quotient, r := new(big.Int).QuoRem(unscaled, ten, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled, scale = quotient, scale-1
+	}
+	return decimal{unscaled: unscaled, scale: scale}
+}
+
+func (d decimal) String() string {
+	digits := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+
+	if d.unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,87 @@
+package normalizejson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDecimal(t *testing.T) {
+	dir := "format_decimal"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DecimalFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{UseNumber: true})
+
+	formatted, err := provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+
+	// compare with json.Number to check the exact digits.
+	expectedItem, err := unmarshalJSON(result, FormatConfig{UseNumber: true})
+	if err != nil {
+		panic(err)
+	}
+
+	formattedItem, err := unmarshalJSON(formatted, FormatConfig{UseNumber: true})
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, expectedItem, formattedItem)
+}
+
+func TestFormatDecimalFailed(t *testing.T) {
+	template := []byte(`{"count":{"__function":"to_int","bits":8},"shares":"to_big_int","amount":"to_decimal"}`)
+	provider, err := NewFormatProvider(template, DecimalFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{UseNumber: true})
+
+	_, err = provider.FormatJSONSchema([]byte(`{"count":128}`))
+	assert.NotNil(t, err)
+
+	_, err = provider.FormatJSONSchema([]byte(`{"count":-128}`))
+	assert.Nil(t, err)
+
+	_, err = provider.FormatJSONSchema([]byte(`{"shares":"12.5"}`))
+	assert.NotNil(t, err)
+
+	_, err = provider.FormatJSONSchema([]byte(`{"amount":"1/3"}`))
+	assert.NotNil(t, err)
+
+	_, err = NewFormatProvider([]byte(`{"amount":{"__function":"to_decimal","rounding":"random"}}`), DecimalFormatDataOptions...)
+	assert.NotNil(t, err)
+
+	// the huge exponents are refused rather than expanded.
+	for _, source := range []string{`{"amount":1e100000000}`, `{"amount":1e-100000000}`, `{"shares":"1e99999999999"}`} {
+		_, err = provider.FormatJSONSchema([]byte(source))
+		var formatErr *FormatError
+		assert.True(t, errors.As(err, &formatErr), source)
+	}
+
+	_, err = provider.FormatJSONSchema([]byte(`{"amount":1e10000}`))
+	assert.Nil(t, err)
+
+	_, err = NewFormatProvider([]byte(`{"amount":{"__function":"to_decimal","scale":100000000}}`), DecimalFormatDataOptions...)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,197 @@
+package normalizejson
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FormatBase64Encode = "base64_encode"
+	FormatBase64Decode = "base64_decode"
+	FormatHexEncode    = "hex_encode"
+	FormatHexDecode    = "hex_decode"
+	FormatURLEncode    = "url_encode"
+	FormatURLDecode    = "url_decode"
+)
+
+const (
+	base64EncodingStd    = "std"
+	base64EncodingURL    = "url"
+	base64EncodingRawStd = "raw_std"
+	base64EncodingRawURL = "raw_url"
+
+	urlEncodingQuery = "query"
+	urlEncodingPath  = "path"
+)
+
+// EncodingFormatDataOptions are the data-options to encode and decode the strings. The decoded bytes are taken as
+// string, so the blobs should be valid UTF-8 text, and the others are regarded as errors rather than corrupted.
+//
+// The parameters in function leaf of template:
+//
+//   - "base64_encode", "base64_decode": "encoding" is one of "std" (default), "url", "raw_std" and "raw_url".
+//     "base64_decode" accepts the strings with or without padding.
+//   - "hex_encode", "hex_decode": "upper" outputs the uppercase hex digits.
+//   - "url_encode", "url_decode": "mode" is "query" (default) or "path".
+//
+// The templates take the directive "__decode_json" to decode the JSON serialized as a string, and "__encode_json" to
+// serialize the value, e.g. {"payload":{"__decode_json":{"amount":"to_float64"}}}.
+var EncodingFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatBase64Encode, BuildFormatDataBase64Encode),
+	FormatDataBuilderOption(FormatBase64Decode, BuildFormatDataBase64Decode),
+	FormatDataBuilderOption(FormatHexEncode, BuildFormatDataHexEncode),
+	FormatDataBuilderOption(FormatHexDecode, BuildFormatDataHexDecode),
+	FormatDataBuilderOption(FormatURLEncode, BuildFormatDataURLEncode),
+	FormatDataBuilderOption(FormatURLDecode, BuildFormatDataURLDecode),
+}
+
+func BuildFormatDataBase64Encode(params map[string]interface{}) (FormatFunc, error) {
+	encoding, err := paramBase64Encoding(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatStringE(item, func(str string) (interface{}, error) {
+			return encoding.EncodeToString([]byte(str)), nil
+		})
+	}, nil
+}
+
+func BuildFormatDataBase64Decode(params map[string]interface{}) (FormatFunc, error) {
+	encoding, err := paramBase64Encoding(params)
+	if err != nil {
+		return nil, err
+	}
+	encoding = encoding.WithPadding(base64.NoPadding)
+
+	return func(item interface{}) (interface{}, error) {
+		return formatStringE(item, func(str string) (interface{}, error) {
+			data, err := encoding.DecodeString(strings.TrimRight(str, "="))
+			if err != nil {
+				return item, fmt.Errorf("decode base64 failed: %s", err)
+			}
+			return decodedString(item, data)
+		})
+	}, nil
+}
+
+// decodedString takes the decoded bytes as string, the bytes out of UTF-8 would be replaced with U+FFFD in JSON.
+func decodedString(item interface{}, data []byte) (interface{}, error) {
+	if !utf8.Valid(data) {
+		return item, fmt.Errorf("decoded bytes are not valid UTF-8")
+	}
+	return string(data), nil
+}
+
+func BuildFormatDataHexEncode(params map[string]interface{}) (FormatFunc, error) {
+	upper, err := paramBool(params, "upper", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatStringE(item, func(str string) (interface{}, error) {
+			encoded := hex.EncodeToString([]byte(str))
+			if upper {
+				encoded = strings.ToUpper(encoded)
+			}
+			return encoded, nil
+		})
+	}, nil
+}
+
+func BuildFormatDataHexDecode(params map[string]interface{}) (FormatFunc, error) {
+	return func(item interface{}) (interface{}, error) {
+		return formatStringE(item, func(str string) (interface{}, error) {
+			data, err := hex.DecodeString(str)
+			if err != nil {
+				return item, fmt.Errorf("decode hex failed: %s", err)
+			}
+			return decodedString(item, data)
+		})
+	}, nil
+}
+
+func BuildFormatDataURLEncode(params map[string]interface{}) (FormatFunc, error) {
+	mode, err := paramURLEncodingMode(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatStringE(item, func(str string) (interface{}, error) {
+			if mode == urlEncodingPath {
+				return url.PathEscape(str), nil
+			}
+			return url.QueryEscape(str), nil
+		})
+	}, nil
+}
+
+func BuildFormatDataURLDecode(params map[string]interface{}) (FormatFunc, error) {
+	mode, err := paramURLEncodingMode(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatStringE(item, func(str string) (interface{}, error) {
+			var decoded string
+			var err error
+			if mode == urlEncodingPath {
+				decoded, err = url.PathUnescape(str)
+			} else {
+				decoded, err = url.QueryUnescape(str)
+			}
+			if err != nil {
+				return item, fmt.Errorf("decode url failed: %s", err)
+			}
+			return decoded, nil
+		})
+	}, nil
+}
+
+func paramBase64Encoding(params map[string]interface{}) (*base64.Encoding, error) {
+	encoding, err := paramString(params, "encoding", base64EncodingStd)
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoding {
+	case base64EncodingStd:
+		return base64.StdEncoding, nil
+	case base64EncodingURL:
+		return base64.URLEncoding, nil
+	case base64EncodingRawStd:
+		return base64.RawStdEncoding, nil
+	case base64EncodingRawURL:
+		return base64.RawURLEncoding, nil
+	default:
+		return nil, fmt.Errorf("unknown base64 encoding %s", encoding)
+	}
+}
+
+func paramURLEncodingMode(params map[string]interface{}) (string, error) {
+	mode, err := paramString(params, "mode", urlEncodingQuery)
+	if err != nil {
+		return "", err
+	}
+	if mode != urlEncodingQuery && mode != urlEncodingPath {
+		return "", fmt.Errorf("unknown url encoding mode %s", mode)
+	}
+	return mode, nil
+}
+
+// formatStringE is formatString whose function may fail.
+func formatStringE(item interface{}, f func(str string) (interface{}, error)) (interface{}, error) {
+	str, ok := item.(string)
+	if !ok {
+		return item, nil
+	}
+	return f(str)
+}
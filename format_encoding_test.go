@@ -0,0 +1,82 @@
+package normalizejson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEncoding(t *testing.T) {
+	dir := "format_encoding"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	var options []FormatOption
+	options = append(options, DefaultFormatDataOptions...)
+	options = append(options, StringFormatDataOptions...)
+	options = append(options, EncodingFormatDataOptions...)
+
+	formatted, err := JSONSchemaFormat(source, template, options...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	source, err = readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err = JSONSchemaFormatData(source, template, options...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestFormatEncodingEmbeddedJSONError(t *testing.T) {
+	template := []byte(`{"events":[{"payload":{"__decode_json":{"amount":"to_float64"}}}]}`)
+
+	_, err := JSONSchemaFormat([]byte(`{"events":[{"payload":"{\"amount\":1}"},{"payload":"{amount"}]}`), template, DefaultFormatDataOptions...)
+	var formatErr *FormatError
+	if assert.True(t, errors.As(err, &formatErr)) {
+		assert.Equal(t, "$.events[1].payload", formatErr.Path)
+	}
+
+	_, err = JSONSchemaFormat([]byte(`{"events":[{"payload":"{\"amount\":\"abc\"}"}]}`), template, DefaultFormatDataOptions...)
+	if assert.True(t, errors.As(err, &formatErr)) {
+		assert.Equal(t, "$.events[0].payload.amount", formatErr.Path)
+	}
+
+	_, err = JSONSchemaFormat([]byte(`{}`), []byte(`{"payload":{"__decode_json":{},"amount":"to_float64"}}`))
+	assert.NotNil(t, err)
+}
+
+func TestFormatEncodingBinaryDecodeError(t *testing.T) {
+	// the decoded bytes out of UTF-8 are regarded as errors instead of being replaced with U+FFFD.
+	template := []byte(`{"blob":"base64_decode","digest":"hex_decode"}`)
+	for _, source := range []string{`{"blob":"/wAB"}`, `{"digest":"ff00"}`} {
+		_, err := JSONSchemaFormat([]byte(source), template, EncodingFormatDataOptions...)
+		var formatErr *FormatError
+		assert.True(t, errors.As(err, &formatErr), source)
+	}
+
+	formatted, err := JSONSchemaFormat([]byte(`{"blob":"aMOp","digest":"6869"}`), template, EncodingFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"blob":"hé","digest":"hi"}`, string(formatted))
+}
@@ -0,0 +1,190 @@
+package normalizejson
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	FormatEnum = "enum"
+)
+
+const (
+	enumUnmatchedPassthrough = "passthrough"
+	enumUnmatchedError       = "error"
+	enumUnmatchedDefault     = "default"
+)
+
+// BuildFormatDataEnum creates the function to map the values to the canonical ones, which is a built-in function of
+// template and requires no option. The parameters in function leaf of template:
+//
+//   - "cases": the list of cases tried in order, each case maps the "values" (compared as string)
+//     or the values matching the regex "patterns" to the "output".
+//   - "case_insensitive": compare the values and match the patterns ignoring case.
+//   - "unmatched": the behavior for the unmatched values, one of "passthrough" (default), "error" and "default".
+//   - "default": the output for the unmatched values with "unmatched" as "default", which must be null or one of the outputs.
+//
+// E.g. {"__function":"enum","cases":[{"values":["active","1","enabled"],"output":"active"},
+// {"patterns":["^disabled?$"],"output":"inactive"}],"case_insensitive":true,"unmatched":"error"}.
+func BuildFormatDataEnum(params map[string]interface{}) (FormatFunc, error) {
+	caseInsensitive, err := paramBool(params, "case_insensitive", false)
+	if err != nil {
+		return nil, err
+	}
+
+	unmatched, err := paramString(params, "unmatched", enumUnmatchedPassthrough)
+	if err != nil {
+		return nil, err
+	}
+	if unmatched != enumUnmatchedPassthrough && unmatched != enumUnmatchedError && unmatched != enumUnmatchedDefault {
+		return nil, fmt.Errorf("unknown unmatched behavior %s", unmatched)
+	}
+	defaultValue := params["default"]
+
+	rawCases, ok := params["cases"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("illegal parameter cases: %v", params["cases"])
+	}
+
+	cases := make([]enumCase, 0, len(rawCases))
+	for _, rawCase := range rawCases {
+		c, err := newEnumCase(rawCase, caseInsensitive)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+
+	if unmatched == enumUnmatchedDefault && defaultValue != nil && !enumHasOutput(cases, defaultValue) {
+		return nil, fmt.Errorf("the default %v is not an output of enum cases", defaultValue)
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		for _, c := range cases {
+			if c.match(item) {
+				return c.output, nil
+			}
+		}
+
+		switch unmatched {
+		case enumUnmatchedError:
+			return item, fmt.Errorf("unmatched enum value %v", item)
+		case enumUnmatchedDefault:
+			return defaultValue, nil
+		default:
+			return item, nil
+		}
+	}, nil
+}
+
+func enumHasOutput(cases []enumCase, value interface{}) bool {
+	for _, c := range cases {
+		if reflect.DeepEqual(c.output, value) {
+			return true
+		}
+	}
+	return false
+}
+
+type enumCase struct {
+	caseInsensitive bool
+	matchNull       bool
+	values          map[string]struct{}
+	patterns        []*regexp.Regexp
+	output          interface{}
+}
+
+func newEnumCase(rawCase interface{}, caseInsensitive bool) (enumCase, error) {
+	params, ok := rawCase.(map[string]interface{})
+	if !ok {
+		return enumCase{}, fmt.Errorf("illegal enum case: %v", rawCase)
+	}
+
+	output, ok := params["output"]
+	if !ok {
+		return enumCase{}, fmt.Errorf("no output in enum case: %v", rawCase)
+	}
+
+	c := enumCase{caseInsensitive: caseInsensitive, values: make(map[string]struct{}), output: output}
+
+	if rawValues, ok := params["values"]; ok {
+		values, ok := rawValues.([]interface{})
+		if !ok {
+			return enumCase{}, fmt.Errorf("illegal values in enum case: %v", rawValues)
+		}
+		for _, value := range values {
+			if value == nil {
+				c.matchNull = true
+				continue
+			}
+			str, ok := enumString(value)
+			if !ok {
+				return enumCase{}, fmt.Errorf("illegal value in enum case: %v", value)
+			}
+			c.values[c.fold(str)] = struct{}{}
+		}
+	}
+
+	patterns, err := paramStringList(params, "patterns", nil)
+	if err != nil {
+		return enumCase{}, err
+	}
+	for _, pattern := range patterns {
+		if caseInsensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return enumCase{}, err
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	return c, nil
+}
+
+func (c enumCase) match(item interface{}) bool {
+	if item == nil {
+		return c.matchNull
+	}
+
+	str, ok := enumString(item)
+	if !ok {
+		return false
+	}
+
+	if _, ok = c.values[c.fold(str)]; ok {
+		return true
+	}
+
+	for _, re := range c.patterns {
+		if re.MatchString(str) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c enumCase) fold(str string) string {
+	if c.caseInsensitive {
+		return strings.ToLower(str)
+	}
+	return str
+}
+
+// enumString takes the string of a JSON scalar value to compare, the objects and arrays are never matched.
+func enumString(item interface{}) (string, bool) {
+	switch item.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", false
+	}
+
+	str, err := cast.ToStringE(item)
+	if err != nil {
+		return "", false
+	}
+	return str, true
+}
@@ -0,0 +1,49 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEnum(t *testing.T) {
+	dir := "format_enum"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	// the function "enum" is built-in, no option is required.
+	formatted, err := JSONSchemaFormat(source, template)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	_, err = JSONSchemaFormat([]byte(`{"accounts":[{"status":"unknown"}]}`), template)
+	assert.NotNil(t, err)
+
+	_, err = JSONSchemaFormat(source, []byte(`{"status":{"__function":"enum","cases":[{"patterns":["("],"output":"x"}]}}`))
+	assert.NotNil(t, err)
+
+	// the default must be null or one of the outputs.
+	_, err = NewFormatProvider([]byte(`{"status":{"__function":"enum","cases":[{"values":["1"],"output":"active"}],"unmatched":"default","default":"unknown"}}`))
+	assert.NotNil(t, err)
+
+	formatted, err = JSONSchemaFormat([]byte(`{"status":"x"}`), []byte(`{"status":{"__function":"enum","cases":[{"values":["1"],"output":"active"},{"values":["0"],"output":"inactive"}],"unmatched":"default","default":"inactive"}}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"status":"inactive"}`, string(formatted))
+}
@@ -0,0 +1,85 @@
+package normalizejson
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// FormatError is the error of formatting a value, with the path of the value in JSON document,
+// such as "$.data.sub_data_list[0].item1".
+type FormatError struct {
+	Path string
+	Err  error
+}
+
+func (e *FormatError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// formatPath is the path of the value in formatting, which is rendered only when an error occurs.
+// The nil path refers to the root of JSON document.
+type formatPath struct {
+	parent *formatPath
+	key    string
+	index  int
+}
+
+func (path *formatPath) child(key string) *formatPath {
+	return &formatPath{parent: path, key: key, index: -1}
+}
+
+func (path *formatPath) element(index int) *formatPath {
+	return &formatPath{parent: path, index: index}
+}
+
+func (path *formatPath) String() string {
+	var segments []string
+	for p := path; p != nil; p = p.parent {
+		switch {
+		case p.index >= 0:
+			segments = append(segments, "["+strconv.Itoa(p.index)+"]")
+		case isPathIdentifier(p.key):
+			segments = append(segments, "."+p.key)
+		default:
+			segments = append(segments, "["+strconv.Quote(p.key)+"]")
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString("$")
+	for index := len(segments) - 1; index >= 0; index-- {
+		builder.WriteString(segments[index])
+	}
+	return builder.String()
+}
+
+func isPathIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for index, r := range key {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (index > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// wrapFormatError attaches the path to err, the error which has a path already is returned as it is.
+func wrapFormatError(path *formatPath, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var formatErr *FormatError
+	if errors.As(err, &formatErr) {
+		return err
+	}
+	return &FormatError{Path: path.String(), Err: err}
+}
@@ -0,0 +1,454 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// iniDialect is the syntax of the INI-like formats, such as dotenv and INI.
+type iniDialect struct {
+	name      string // the name of format in errors.
+	sections  bool   // take the lines like "[section]" as the headers of sections.
+	export    bool   // allow the "export " prefix of keys.
+	colon     bool   // allow ":" as the separator of keys and values, besides "=".
+	comments  string // the characters starting the comments.
+	separator string // the separator of the new entries.
+}
+
+var dotenvDialect = iniDialect{name: "dotenv", export: true, comments: "#", separator: "="}
+
+var iniFileDialect = iniDialect{name: "INI", sections: true, colon: true, comments: ";#", separator: " = "}
+
+type iniLineKind int
+
+const (
+	iniLineOther iniLineKind = iota // the blank lines and comments
+	iniLineSection
+	iniLineEntry
+)
+
+type iniLine struct {
+	kind      iniLineKind
+	raw       string
+	section   string // the name of section header, or the section of entry, which is empty for the global ones.
+	key       string
+	value     string
+	prefix    string // the text before key, e.g. the indent and "export ".
+	separator string // the separator with the spaces around it, e.g. " = ".
+	quote     byte   // the quote of value, zero for the unquoted ones.
+	suffix    string // the inline comment with the spaces before it.
+}
+
+// FormatDotenv normalizes the dotenv file with provider, in which the values are taken as JSON strings,
+// e.g. {"__keys":"screaming_snake_to_camel","dbPort":"to_int64"}.
+// The comments, the order of lines and the quotes of values are preserved, and the renamed keys are matched with
+// the original ones ignoring case and separators. The new keys are appended in order, the objects and arrays are
+// written as JSON strings, and the nulls as empty values.
+func FormatDotenv(provider FormatProvider, data []byte) ([]byte, error) {
+	return formatINI(provider, data, dotenvDialect)
+}
+
+// FormatINI normalizes the INI file with provider, in which the sections are taken as JSON objects and the values as
+// JSON strings, e.g. {"server":{"port":"to_int64"}}. The keys before the first section are the global ones.
+// The lines are written back as FormatDotenv, and the new sections are appended to the end.
+func FormatINI(provider FormatProvider, data []byte) ([]byte, error) {
+	return formatINI(provider, data, iniFileDialect)
+}
+
+// DotenvToJSON converts the dotenv file to a JSON object of strings.
+func DotenvToJSON(data []byte) ([]byte, error) {
+	return iniToJSON(data, dotenvDialect)
+}
+
+// INIToJSON converts the INI file to a JSON object, in which the sections are objects and the values are strings.
+func INIToJSON(data []byte) ([]byte, error) {
+	return iniToJSON(data, iniFileDialect)
+}
+
+func iniToJSON(data []byte, dialect iniDialect) ([]byte, error) {
+	lines, err := parseINILines(data, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := json.Marshal(iniJSONValue(lines))
+	if err != nil {
+		return nil, fmt.Errorf("marshal JSON failed: %s", err)
+	}
+	return converted, nil
+}
+
+func formatINI(provider FormatProvider, data []byte, dialect iniDialect) ([]byte, error) {
+	lines, err := parseINILines(data, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := formatValueByProvider(provider, iniJSONValue(lines))
+	if err != nil {
+		return nil, err
+	}
+
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("marshal %s failed: the result should be an object, but got %T", dialect.name, value)
+	}
+	return writeINILines(lines, valueMap, dialect)
+}
+
+// iniJSONValue takes the entries as a JSON object, the later values override the former ones of the same keys.
+func iniJSONValue(lines []iniLine) map[string]interface{} {
+	value := make(map[string]interface{})
+	for _, line := range lines {
+		switch line.kind {
+		case iniLineSection:
+			if _, ok := value[line.section].(map[string]interface{}); !ok {
+				value[line.section] = make(map[string]interface{})
+			}
+		case iniLineEntry:
+			if line.section == "" {
+				value[line.key] = line.value
+			} else {
+				value[line.section].(map[string]interface{})[line.key] = line.value
+			}
+		}
+	}
+	return value
+}
+
+func parseINILines(data []byte, dialect iniDialect) ([]iniLine, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil, nil
+	}
+
+	var lines []iniLine
+	var section string
+	for index, raw := range strings.Split(text, "\n") {
+		line, err := parseINILine(raw, dialect)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal source %s failed: line %d: %s", dialect.name, index+1, err)
+		}
+
+		switch line.kind {
+		case iniLineSection:
+			section = line.section
+		case iniLineEntry:
+			line.section = section
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func parseINILine(raw string, dialect iniDialect) (iniLine, error) {
+	line := iniLine{kind: iniLineOther, raw: raw}
+
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || strings.ContainsAny(trimmed[:1], dialect.comments) {
+		return line, nil
+	}
+
+	if dialect.sections && strings.HasPrefix(trimmed, "[") {
+		end := strings.IndexByte(trimmed, ']')
+		if end < 0 || strings.TrimSpace(trimmed[1:end]) == "" || !isINIComment(trimmed[end+1:], dialect) {
+			return line, fmt.Errorf("illegal section header %q", trimmed)
+		}
+		line.kind = iniLineSection
+		line.section = strings.TrimSpace(trimmed[1:end])
+		return line, nil
+	}
+
+	separators := "="
+	if dialect.colon {
+		separators += ":"
+	}
+	index := strings.IndexAny(raw, separators)
+	if index < 0 {
+		return line, fmt.Errorf("missing separator in %q", trimmed)
+	}
+
+	keyText, valueText := raw[:index], raw[index+1:]
+	key := strings.TrimSpace(keyText)
+	line.prefix = keyText[:len(keyText)-len(strings.TrimLeft(keyText, " \t"))]
+	if dialect.export && strings.HasPrefix(key, "export ") {
+		line.prefix += key[:len(key)-len(strings.TrimLeft(key[len("export"):], " \t"))]
+		key = strings.TrimLeft(key[len("export"):], " \t")
+	}
+	if key == "" {
+		return line, fmt.Errorf("missing key in %q", trimmed)
+	}
+
+	value := strings.TrimLeft(valueText, " \t")
+	line.kind = iniLineEntry
+	line.key = key
+	line.separator = keyText[len(strings.TrimRight(keyText, " \t")):] + raw[index:index+1] + valueText[:len(valueText)-len(value)]
+
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		unquoted, rest, err := unquoteINIValue(value)
+		if err != nil {
+			return line, err
+		}
+		if !isINIComment(rest, dialect) {
+			return line, fmt.Errorf("unexpected %q after quoted value", strings.TrimSpace(rest))
+		}
+		line.value, line.quote, line.suffix = unquoted, value[0], rest
+		return line, nil
+	}
+
+	// the inline comments start with a comment character after spaces.
+	end := len(value)
+	for index := 1; index < len(value); index++ {
+		if strings.IndexByte(dialect.comments, value[index]) >= 0 && (value[index-1] == ' ' || value[index-1] == '\t') {
+			end = index
+			break
+		}
+	}
+	line.value = strings.TrimRight(value[:end], " \t")
+	line.suffix = value[len(line.value):]
+	return line, nil
+}
+
+// unquoteINIValue unquotes the value, the double-quoted one supports the escapes like \n, \" and \\.
+func unquoteINIValue(value string) (string, string, error) {
+	quote := value[0]
+	var builder strings.Builder
+	for index := 1; index < len(value); index++ {
+		c := value[index]
+		switch {
+		case c == quote:
+			return builder.String(), value[index+1:], nil
+		case c == '\\' && quote == '"' && index+1 < len(value):
+			index++
+			switch value[index] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case '"', '\\':
+				builder.WriteByte(value[index])
+			default:
+				builder.WriteByte('\\')
+				builder.WriteByte(value[index])
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quote in %s", value)
+}
+
+func isINIComment(text string, dialect iniDialect) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || strings.ContainsAny(text[:1], dialect.comments)
+}
+
+// iniBlock is the lines of a section, or the global lines before the first section.
+type iniBlock struct {
+	section string // the formatted name of section.
+	header  *iniLine
+	lines   []iniLine
+}
+
+// writeINILines writes the formatted value back with the original lines, the lines of the dropped keys and sections
+// are omitted, and the new keys are appended after the last entry of their sections.
+func writeINILines(lines []iniLine, value map[string]interface{}, dialect iniDialect) ([]byte, error) {
+	entries := make(map[string]interface{})
+	sections := make(map[string]interface{})
+	for key, item := range value {
+		if _, ok := item.(map[string]interface{}); ok && dialect.sections {
+			sections[key] = item
+		} else {
+			entries[key] = item
+		}
+	}
+	sectionKeys := sortedKeys(sections)
+
+	// split the lines into blocks, and match the sections with the formatted ones.
+	blocks := []*iniBlock{{}}
+	matchedSections := make(map[string]string)
+	usedSections := make(map[string]bool)
+	for index := range lines {
+		line := lines[index]
+		if line.kind != iniLineSection {
+			block := blocks[len(blocks)-1]
+			block.lines = append(block.lines, line)
+			continue
+		}
+
+		formattedSection, ok := matchedSections[line.section]
+		if !ok {
+			if formattedSection, ok = matchFormattedKey(line.section, sectionKeys, usedSections); ok {
+				usedSections[formattedSection] = true
+				matchedSections[line.section] = formattedSection
+			}
+		}
+		if !ok {
+			formattedSection = ""
+		}
+		blocks = append(blocks, &iniBlock{section: formattedSection, header: &lines[index]})
+	}
+
+	// the new keys are appended to the last block of each section.
+	lastBlocks := make(map[string]*iniBlock)
+	for _, block := range blocks {
+		if block.header == nil || block.section != "" {
+			lastBlocks[block.section] = block
+		}
+	}
+
+	usedKeys := make(map[string]map[string]bool)
+	var builder strings.Builder
+	for _, block := range blocks {
+		if block.header != nil && block.section == "" {
+			// the section is dropped.
+			continue
+		}
+
+		blockEntries := entries
+		if block.header != nil {
+			blockEntries = sections[block.section].(map[string]interface{})
+			writeINIHeader(&builder, *block.header, block.section)
+		}
+		keys := sortedKeys(blockEntries)
+		used := usedKeys[block.section]
+		if used == nil {
+			used = make(map[string]bool)
+			usedKeys[block.section] = used
+		}
+
+		// the new keys are inserted after the last entry, or at the end of the global lines without entries.
+		insertAt := len(block.lines)
+		if block.header != nil {
+			insertAt = 0
+		}
+		for index, line := range block.lines {
+			if line.kind == iniLineEntry {
+				insertAt = index + 1
+			}
+		}
+
+		for index, line := range block.lines {
+			if index == insertAt && lastBlocks[block.section] == block {
+				if err := writeINIEntries(&builder, blockEntries, keys, used, dialect); err != nil {
+					return nil, err
+				}
+			}
+			if line.kind != iniLineEntry {
+				builder.WriteString(line.raw)
+				builder.WriteByte('\n')
+				continue
+			}
+
+			key, ok := matchFormattedKey(line.key, keys, used)
+			if !ok {
+				continue
+			}
+			used[key] = true
+			if err := writeINIEntry(&builder, line, key, blockEntries[key], dialect); err != nil {
+				return nil, err
+			}
+		}
+		if insertAt == len(block.lines) && lastBlocks[block.section] == block {
+			if err := writeINIEntries(&builder, blockEntries, keys, used, dialect); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, section := range sectionKeys {
+		if usedSections[section] {
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteByte('\n')
+		}
+		writeINIHeader(&builder, iniLine{}, section)
+		sectionEntries := sections[section].(map[string]interface{})
+		if err := writeINIEntries(&builder, sectionEntries, sortedKeys(sectionEntries), map[string]bool{}, dialect); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(builder.String()), nil
+}
+
+func writeINIHeader(builder *strings.Builder, header iniLine, section string) {
+	if header.section == section {
+		builder.WriteString(header.raw)
+	} else {
+		builder.WriteString("[" + section + "]")
+	}
+	builder.WriteByte('\n')
+}
+
+// writeINIEntries writes the entries of keys which are not used.
+func writeINIEntries(builder *strings.Builder, entries map[string]interface{}, keys []string, used map[string]bool, dialect iniDialect) error {
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		used[key] = true
+		if err := writeINIEntry(builder, iniLine{separator: dialect.separator}, key, entries[key], dialect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeINIEntry writes the entry with the layout of line, the line is retained if neither the key nor the value changes.
+func writeINIEntry(builder *strings.Builder, line iniLine, key string, value interface{}, dialect iniDialect) error {
+	str, err := iniString(value)
+	if err != nil {
+		return fmt.Errorf("marshal %s failed: %s", dialect.name, err)
+	}
+
+	if line.kind == iniLineEntry && line.key == key && line.value == str {
+		builder.WriteString(line.raw)
+	} else {
+		builder.WriteString(line.prefix + key + line.separator + quoteINIValue(str, line.quote, dialect) + line.suffix)
+	}
+	builder.WriteByte('\n')
+	return nil
+}
+
+func iniString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// quoteINIValue quotes the value if necessary, and the original quote is preferred.
+func quoteINIValue(value string, quote byte, dialect iniDialect) string {
+	if quote == '\'' && !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+
+	needQuote := quote == '"' || value != strings.TrimSpace(value) || strings.ContainsAny(value, "\"'\\\n\r\t"+dialect.comments)
+	if dialect.export && strings.Contains(value, " ") {
+		needQuote = true
+	}
+	if !needQuote {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,123 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDotenv(t *testing.T) {
+	dir := "format_dotenv"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.env")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.env")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatDotenv(provider, source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, string(result), string(formatted))
+
+	// convert the keys back.
+	provider, err = NewFormatProvider([]byte(`{"__keys":"camel_to_screaming_snake"}`))
+	if err != nil {
+		panic(err)
+	}
+	formatted, err = FormatDotenv(provider, formatted)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "# database\nexport DB_HOST=localhost # the host\nDB_PORT = \"5432\"\n\n# application\nDEBUG='true'\nAPP_NAME=\"demo \\\"app\\\"\"\nEMPTY=\n", string(formatted))
+}
+
+func TestFormatINI(t *testing.T) {
+	dir := "format_ini"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.ini")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.ini")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatINI(provider, source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, string(result), string(formatted))
+}
+
+func TestFormatINILargeIntegers(t *testing.T) {
+	// the integers beyond 2^53 are exact, whatever the config of provider is.
+	provider, err := NewFormatDataProvider([]byte(`{"server":{"id":"to_int64"}}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatINI(provider, []byte("[server]\nid = 9007199254740993\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "[server]\nid = 9007199254740993\n", string(formatted))
+}
+
+func TestINIToJSON(t *testing.T) {
+	converted, err := INIToJSON([]byte("name = demo\n[server]\nport: 8080\nurl = \"http://a#b\" # comment\n[server]\nhost = a\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"name":"demo","server":{"port":"8080","url":"http://a#b","host":"a"}}`)), formatJSON(converted))
+
+	converted, err = DotenvToJSON([]byte("export A=1\nB=\"x\\ny\"\nC='$HOME'\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"A":"1","B":"x\ny","C":"$HOME"}`)), formatJSON(converted))
+
+	_, err = DotenvToJSON([]byte("A=\"unterminated\n"))
+	assert.NotNil(t, err)
+
+	_, err = INIToJSON([]byte("[server\n"))
+	assert.NotNil(t, err)
+}
+
+func TestFormatKeyScreamingSnake(t *testing.T) {
+	for key, expected := range map[string]string{"dbHost": "DB_HOST", "DB_HOST": "DB_HOST", "port": "PORT"} {
+		formatted, err := FormatKeyCamelToScreamingSnake(key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, formatted)
+	}
+	for key, expected := range map[string]string{"DB_HOST": "dbHost", "PORT": "port"} {
+		formatted, err := FormatKeyScreamingSnakeToCamel(key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, formatted)
+	}
+}
@@ -0,0 +1,654 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TemplateFromJSONSchema generates the template of schema provider from the JSON Schema document, whose root schema
+// should describe an object. The functions in the template are the ones of DefaultFormatDataOptions,
+// TimeFormatDataOptions and ValidateFormatDataOptions, which the provider should take.
+//
+//   - "type" takes "to_string", "to_int64", "to_float64" and "to_bool", and "format" takes "to_rfc3339" for
+//     "date-time", "to_time" for "date", "email", "url" for "uri", "uuid" and "ip" for "ipv4" and "ipv6".
+//   - "enum" and "const" take the function "enum" mapping the values case-insensitively to the listed ones, and the
+//     unmatched values are regarded as errors.
+//   - The nullable values are preserved, the null values of other required properties are regarded as errors, and
+//     the ones of other optional properties are dropped, or take the "default" of the property.
+//   - The definitions in "$defs" (or "definitions") describing objects or arrays are taken as the templates named
+//     "__def_{{name}}" at root, which "$ref" refers to with "__template.__def_{{name}}", and the others are inlined.
+//     The JSON keys with the prefix "__def_" never take these templates, so the definitions apply only where they
+//     are referred. The definitions referring to themselves should describe objects or arrays.
+//   - "oneOf", "anyOf" and "allOf" take the templates in common, e.g. the properties of all the objects.
+//   - "additionalProperties" with a schema takes the directive "__additional", the extra properties are kept anyway.
+//
+// E.g. {"type":"object","properties":{"id":{"type":"integer"}},"required":["id"]} generates
+// {"id":{"__function":"to_int64","__null":"error"}}.
+func TemplateFromJSONSchema(schema []byte) ([]byte, error) {
+	document, err := unmarshalJSON(schema, FormatConfig{UseNumber: true})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal JSON Schema failed: %s", err)
+	}
+
+	template, err := newJSONSchemaConverter(document).convertRoot()
+	if err != nil {
+		return nil, fmt.Errorf("convert JSON Schema failed: %s", err)
+	}
+	return json.Marshal(template)
+}
+
+const (
+	jsonSchemaRefPrefix = "#/"
+)
+
+// jsonSchemaConverter converts the schemas of a JSON Schema document to templates.
+type jsonSchemaConverter struct {
+	document  interface{}
+	defs      map[string]string      // the names of templates by the pointers of definitions.
+	templates map[string]interface{} // the templates of definitions by their names.
+	resolving map[string]bool        // the pointers being resolved, to stop the recursive references.
+}
+
+func newJSONSchemaConverter(document interface{}) *jsonSchemaConverter {
+	return &jsonSchemaConverter{
+		document:  document,
+		defs:      make(map[string]string),
+		templates: make(map[string]interface{}),
+		resolving: make(map[string]bool),
+	}
+}
+
+func (c *jsonSchemaConverter) convertRoot() (map[string]interface{}, error) {
+	root, ok := c.document.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("illegal schema: %v", c.document)
+	}
+
+	schema, err := c.deref(root)
+	if err != nil {
+		return nil, err
+	}
+	if schemaType(schema) != "object" {
+		return nil, fmt.Errorf("the root schema should describe an object")
+	}
+
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, _ := root[key].(map[string]interface{})
+		for _, name := range sortedKeys(defs) {
+			pointer := jsonSchemaRefPrefix + key + "/" + escapeJSONPointer(name)
+			c.defs[pointer] = c.templateName(name)
+		}
+	}
+
+	template, err := c.convertObject(schema)
+	if err != nil {
+		return nil, err
+	}
+	// the extra properties of root cannot be described, since the root template holds the definitions.
+	delete(template, formatSchemaAdditionalDirective)
+
+	// the definitions referred by name are converted along with the root.
+	for name, defTemplate := range c.templates {
+		if !isNamedTemplate(defTemplate) {
+			continue
+		}
+		if _, ok := template[name]; ok {
+			return nil, fmt.Errorf("the property %s conflicts with the template of definition", name)
+		}
+		template[name] = defTemplate
+	}
+	return template, nil
+}
+
+// templateName names the template of definition with the prefix formatSchemaDefPrefix, and the conflicts between
+// "$defs" and "definitions" take the names with suffixes.
+func (c *jsonSchemaConverter) templateName(name string) string {
+	candidate := formatSchemaDefPrefix + name
+	for index := 1; c.isTemplateName(candidate); index++ {
+		candidate = formatSchemaDefPrefix + name + "_" + strconv.Itoa(index)
+	}
+	return candidate
+}
+
+func (c *jsonSchemaConverter) isTemplateName(name string) bool {
+	for _, templateName := range c.defs {
+		if templateName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamedTemplate checks whether the template of definition is referred by name, the function leaves are inlined.
+func isNamedTemplate(template interface{}) bool {
+	switch template.(type) {
+	case map[string]interface{}, []interface{}:
+		return !isFunctionLeaf(template)
+	default:
+		return false
+	}
+}
+
+func isFunctionLeaf(template interface{}) bool {
+	templateMap, ok := template.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = templateMap[formatTemplateFunctionDirective]
+	return ok
+}
+
+// convertDef converts the definition once, the template is nil while converting it.
+func (c *jsonSchemaConverter) convertDef(pointer string) (interface{}, error) {
+	name := c.defs[pointer]
+	if template, ok := c.templates[name]; ok && template != nil {
+		return template, nil
+	}
+	if c.resolving[pointer] {
+		return nil, nil
+	}
+
+	schema, err := c.resolve(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	c.resolving[pointer] = true
+	c.templates[name] = nil
+	template, err := c.convert(schema)
+	delete(c.resolving, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", pointer, err)
+	}
+	// the definition referring to itself should be an object or array, otherwise the reference cannot be resolved.
+	if !isNamedTemplate(template) && refersTemplate(template, name) {
+		return nil, fmt.Errorf("%s: recursive $ref without object or array", pointer)
+	}
+	c.templates[name] = template
+	return template, nil
+}
+
+// refersTemplate checks whether the template refers to the template named by name.
+func refersTemplate(template interface{}, name string) bool {
+	switch v := template.(type) {
+	case string:
+		return v == formatDataTemplatePrefix+name
+	case map[string]interface{}:
+		for _, item := range v {
+			if refersTemplate(item, name) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if refersTemplate(item, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve finds the schema referred by the JSON pointer in the document.
+func (c *jsonSchemaConverter) resolve(ref string) (map[string]interface{}, error) {
+	if ref == "#" {
+		schema, _ := c.document.(map[string]interface{})
+		return schema, nil
+	}
+	if !strings.HasPrefix(ref, jsonSchemaRefPrefix) {
+		return nil, fmt.Errorf("unsupported $ref %s", ref)
+	}
+
+	var node = c.document
+	for _, token := range strings.Split(strings.TrimPrefix(ref, jsonSchemaRefPrefix), "/") {
+		token, err := url.PathUnescape(token)
+		if err != nil {
+			return nil, fmt.Errorf("illegal $ref %s", ref)
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch v := node.(type) {
+		case map[string]interface{}:
+			node = v[token]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("unresolved $ref %s", ref)
+			}
+			node = v[index]
+		default:
+			node = nil
+		}
+	}
+
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unresolved $ref %s", ref)
+	}
+	return schema, nil
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// deref follows the references to the schema describing the value.
+func (c *jsonSchemaConverter) deref(schema map[string]interface{}) (map[string]interface{}, error) {
+	for depth := 0; ; depth++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema, nil
+		}
+		if depth > 100 {
+			return nil, fmt.Errorf("recursive $ref %s", ref)
+		}
+
+		var err error
+		if schema, err = c.resolve(ref); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// convert converts the schema to template, and nil means the value is kept as it is.
+func (c *jsonSchemaConverter) convert(schema interface{}) (interface{}, error) {
+	schemaMap, ok := schema.(map[string]interface{})
+	if !ok {
+		// the boolean schemas accept any value or nothing.
+		return nil, nil
+	}
+
+	if ref, ok := schemaMap["$ref"].(string); ok {
+		return c.convertRef(ref)
+	}
+
+	if _, ok := schemaMap["enum"]; ok {
+		return convertEnum(schemaMap["enum"])
+	}
+	if value, ok := schemaMap["const"]; ok {
+		return convertEnum([]interface{}{value})
+	}
+
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if schemas, ok := schemaMap[key].([]interface{}); ok {
+			return c.convertCombined(schemas)
+		}
+	}
+
+	switch schemaType(schemaMap) {
+	case "object":
+		return c.convertObject(schemaMap)
+	case "array":
+		items, err := c.convert(schemaMap["items"])
+		if err != nil || items == nil {
+			return nil, err
+		}
+		return []interface{}{items}, nil
+	case "string":
+		return convertString(schemaMap), nil
+	case "integer":
+		return FormatToInt64, nil
+	case "number":
+		return FormatToFloat64, nil
+	case "boolean":
+		return FormatToBool, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (c *jsonSchemaConverter) convertRef(ref string) (interface{}, error) {
+	if _, ok := c.defs[ref]; !ok {
+		schema, err := c.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		// the schemas other than definitions are inlined, stopping at the recursive ones.
+		if c.resolving[ref] {
+			return nil, nil
+		}
+		c.resolving[ref] = true
+		defer delete(c.resolving, ref)
+		return c.convert(schema)
+	}
+
+	template, err := c.convertDef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil && c.resolving[ref] {
+		// the definition is being converted, which is an object or array referring to itself.
+		return formatDataTemplatePrefix + c.defs[ref], nil
+	}
+	if isNamedTemplate(template) {
+		return formatDataTemplatePrefix + c.defs[ref], nil
+	}
+	return template, nil
+}
+
+// schemaType takes the type of schema without null, which is inferred from the keywords if absent.
+func schemaType(schema map[string]interface{}) string {
+	switch v := schema["type"].(type) {
+	case string:
+		return v
+	case []interface{}:
+		var types []string
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "null" {
+				types = append(types, str)
+			}
+		}
+		if len(types) == 1 {
+			return types[0]
+		}
+		return ""
+	}
+
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+func convertString(schema map[string]interface{}) interface{} {
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date-time":
+		return FormatToRFC3339
+	case "date":
+		return map[string]interface{}{formatTemplateFunctionDirective: FormatToTime, "output": "date"}
+	case "email", "idn-email":
+		return FormatEmail
+	case "uri", "iri":
+		return FormatURL
+	case "uuid":
+		return FormatUUID
+	case ipVersion4, ipVersion6:
+		return map[string]interface{}{formatTemplateFunctionDirective: FormatIP, "version": format}
+	default:
+		return FormatToString
+	}
+}
+
+// convertEnum maps the values to the listed ones, the null values are left to the null policy.
+func convertEnum(rawValues interface{}) (interface{}, error) {
+	values, ok := rawValues.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("illegal enum: %v", rawValues)
+	}
+
+	caseInsensitive := true
+	folded := make(map[string]bool, len(values))
+	cases := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		str, ok := enumString(value)
+		if !ok {
+			// the objects and arrays cannot be compared.
+			return nil, nil
+		}
+		if folded[strings.ToLower(str)] {
+			caseInsensitive = false
+		}
+		folded[strings.ToLower(str)] = true
+		cases = append(cases, map[string]interface{}{"values": []interface{}{value}, "output": value})
+	}
+	if len(cases) == 0 {
+		return nil, nil
+	}
+
+	return map[string]interface{}{
+		formatTemplateFunctionDirective: FormatEnum,
+		"cases":                         cases,
+		"case_insensitive":              caseInsensitive,
+		"unmatched":                     enumUnmatchedError,
+	}, nil
+}
+
+func (c *jsonSchemaConverter) convertObject(schema map[string]interface{}) (map[string]interface{}, error) {
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, item := range list {
+			if name, ok := item.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	template := make(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		propertyTemplate, err := c.convertProperty(properties[name], required[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		if propertyTemplate != nil {
+			template[name] = propertyTemplate
+		}
+	}
+
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		additionalTemplate, err := c.convertProperty(additional, false)
+		if err != nil {
+			return nil, fmt.Errorf("additionalProperties: %s", err)
+		}
+		if additionalTemplate != nil {
+			template[formatSchemaAdditionalDirective] = additionalTemplate
+		}
+	}
+	return template, nil
+}
+
+// convertProperty converts the schema of property, and takes the null policy for the function leaf.
+func (c *jsonSchemaConverter) convertProperty(schema interface{}, required bool) (interface{}, error) {
+	template, err := c.convert(schema)
+	if err != nil || template == nil {
+		return template, err
+	}
+
+	var leaf map[string]interface{}
+	switch v := template.(type) {
+	case string:
+		if strings.HasPrefix(v, formatDataTemplatePrefix) {
+			return template, nil
+		}
+		leaf = map[string]interface{}{formatTemplateFunctionDirective: v}
+	case map[string]interface{}:
+		if !isFunctionLeaf(v) {
+			return template, nil
+		}
+		leaf = make(map[string]interface{}, len(v)+1)
+		for key, value := range v {
+			leaf[key] = value
+		}
+	default:
+		return template, nil
+	}
+
+	schemaMap, _ := schema.(map[string]interface{})
+	nullable, err := c.isNullable(schemaMap)
+	if err != nil {
+		return nil, err
+	}
+	defaultValue, hasDefault := schemaMap["default"]
+	switch {
+	case nullable:
+		leaf[formatTemplateNullDirective] = NullPolicyPreserve
+	case hasDefault && defaultValue != nil:
+		leaf[formatTemplateDefaultDirective] = defaultValue
+	case required:
+		leaf[formatTemplateNullDirective] = NullPolicyError
+	default:
+		leaf[formatTemplateNullDirective] = NullPolicyDrop
+	}
+	return leaf, nil
+}
+
+// isNullable checks whether the schema accepts null.
+func (c *jsonSchemaConverter) isNullable(schema map[string]interface{}) (bool, error) {
+	if schema == nil {
+		return false, nil
+	}
+	schema, err := c.deref(schema)
+	if err != nil {
+		return false, err
+	}
+
+	if nullable, ok := schema["nullable"].(bool); ok && nullable {
+		return true, nil
+	}
+	switch v := schema["type"].(type) {
+	case string:
+		return v == "null", nil
+	case []interface{}:
+		for _, item := range v {
+			if item == "null" {
+				return true, nil
+			}
+		}
+	}
+	if values, ok := schema["enum"].([]interface{}); ok {
+		for _, value := range values {
+			if value == nil {
+				return true, nil
+			}
+		}
+	}
+	if value, ok := schema["const"]; ok && value == nil {
+		return true, nil
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		schemas, _ := schema[key].([]interface{})
+		for _, item := range schemas {
+			itemMap, _ := item.(map[string]interface{})
+			if nullable, err := c.isNullable(itemMap); err != nil || nullable {
+				return nullable, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// convertCombined takes the template in common of the schemas, the ones accepting only null are ignored.
+func (c *jsonSchemaConverter) convertCombined(schemas []interface{}) (interface{}, error) {
+	var templates []interface{}
+	for _, schema := range schemas {
+		if schemaMap, ok := schema.(map[string]interface{}); ok && schemaMap["type"] == "null" {
+			continue
+		}
+		template, err := c.convert(schema)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return c.mergeTemplates(templates)
+}
+
+func (c *jsonSchemaConverter) mergeTemplates(templates []interface{}) (interface{}, error) {
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	if len(templates) == 1 {
+		return templates[0], nil
+	}
+
+	// the enums are merged into one.
+	if isEnumLeaf(templates[0]) {
+		var values []interface{}
+		for _, template := range templates {
+			if !isEnumLeaf(template) {
+				return nil, nil
+			}
+			for _, enumCase := range template.(map[string]interface{})["cases"].([]interface{}) {
+				values = append(values, enumCase.(map[string]interface{})["output"])
+			}
+		}
+		enum, err := convertEnum(values)
+		if err != nil || enum == nil {
+			return enum, err
+		}
+		// the properties take the null policy of the first one.
+		for _, key := range []string{formatTemplateNullDirective, formatTemplateDefaultDirective} {
+			if value, ok := templates[0].(map[string]interface{})[key]; ok {
+				enum.(map[string]interface{})[key] = value
+			}
+		}
+		return enum, nil
+	}
+
+	// the objects take the properties of all of them, except the ones with different templates.
+	objects := make([]map[string]interface{}, 0, len(templates))
+	for _, template := range templates {
+		object, ok := c.objectTemplate(template)
+		if !ok {
+			return mergeSameTemplates(templates), nil
+		}
+		objects = append(objects, object)
+	}
+
+	merged := make(map[string]interface{})
+	for _, object := range objects {
+		for key, template := range object {
+			existTemplate, ok := merged[key]
+			if !ok || sameTemplate(existTemplate, template) {
+				merged[key] = template
+				continue
+			}
+			// the different templates of a property are merged, or the property is kept as it is.
+			mergedTemplate, err := c.mergeTemplates([]interface{}{existTemplate, template})
+			if err != nil {
+				return nil, err
+			}
+			merged[key] = mergedTemplate
+		}
+	}
+	for key, template := range merged {
+		if template == nil {
+			delete(merged, key)
+		}
+	}
+	return merged, nil
+}
+
+// objectTemplate takes the template of object, which may be referred by name.
+func (c *jsonSchemaConverter) objectTemplate(template interface{}) (map[string]interface{}, bool) {
+	if expr, ok := template.(string); ok && strings.HasPrefix(expr, formatDataTemplatePrefix) {
+		template = c.templates[strings.TrimPrefix(expr, formatDataTemplatePrefix)]
+	}
+	object, ok := template.(map[string]interface{})
+	if !ok || isFunctionLeaf(object) {
+		return nil, false
+	}
+	return object, true
+}
+
+func isEnumLeaf(template interface{}) bool {
+	templateMap, ok := template.(map[string]interface{})
+	return ok && templateMap[formatTemplateFunctionDirective] == FormatEnum
+}
+
+// mergeSameTemplates takes the template if all of them are the same, such as the branches with different formats.
+func mergeSameTemplates(templates []interface{}) interface{} {
+	for _, template := range templates[1:] {
+		if !sameTemplate(templates[0], template) {
+			return nil
+		}
+	}
+	return templates[0]
+}
+
+func sameTemplate(a interface{}, b interface{}) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && string(x) == string(y)
+}
@@ -0,0 +1,140 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFromJSONSchema(t *testing.T) {
+	dir := "format_jsonschema"
+	schema, err := readTestData(dir, "schema.json")
+	if err != nil {
+		panic(err)
+	}
+
+	config, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	template, err := TemplateFromJSONSchema(schema)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON(config), formatJSON(template))
+
+	options := append(append(append([]FormatOption{}, DefaultFormatDataOptions...), TimeFormatDataOptions...), ValidateFormatDataOptions...)
+	provider, err := NewFormatProvider(template, options...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	// the required properties take no null.
+	_, err = provider.FormatJSONSchema([]byte(`{"id":null}`))
+	assert.NotNil(t, err)
+
+	_, err = provider.FormatJSONSchema([]byte(`{"status":"refunded"}`))
+	assert.NotNil(t, err)
+}
+
+func TestTemplateFromJSONSchemaDefinitions(t *testing.T) {
+	// the definitions take the names with prefix, which never conflict with the properties.
+	template, err := TemplateFromJSONSchema([]byte(`{
+		"properties": {
+			"node": {"type": "string"},
+			"tree": {"$ref": "#/definitions/node"}
+		},
+		"definitions": {
+			"node": {"type": "object", "properties": {"size": {"type": "integer"}, "children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}
+		}
+	}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{
+		"node": {"__function": "to_string", "__null": "drop"},
+		"tree": "__template.__def_node",
+		"__def_node": {"children": ["__template.__def_node"], "size": {"__function": "to_int64", "__null": "drop"}}
+	}`)), formatJSON(template))
+
+	// the same names in "$defs" and "definitions" take the suffixes.
+	template, err = TemplateFromJSONSchema([]byte(`{
+		"properties": {"a": {"$ref": "#/$defs/node"}, "b": {"$ref": "#/definitions/node"}},
+		"$defs": {"node": {"properties": {"x": {"type": "integer"}}}},
+		"definitions": {"node": {"properties": {"y": {"type": "integer"}}}}
+	}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{
+		"a": "__template.__def_node",
+		"b": "__template.__def_node_1",
+		"__def_node": {"x": {"__function": "to_int64", "__null": "drop"}},
+		"__def_node_1": {"y": {"__function": "to_int64", "__null": "drop"}}
+	}`)), formatJSON(template))
+
+	_, err = TemplateFromJSONSchema([]byte(`{"properties":{"__def_node":{"type":"string"},"a":{"$ref":"#/$defs/node"}},"$defs":{"node":{"type":"object"}}}`))
+	assert.NotNil(t, err)
+
+	_, err = TemplateFromJSONSchema([]byte(`{"type":"array","items":{"type":"string"}}`))
+	assert.NotNil(t, err)
+
+	_, err = TemplateFromJSONSchema([]byte(`{"properties":{"id":{"$ref":"https://example.com/id.json"}}}`))
+	assert.NotNil(t, err)
+}
+
+func TestTemplateFromJSONSchemaRecursive(t *testing.T) {
+	// the recursive references without objects or arrays cannot be resolved.
+	for _, schema := range []string{
+		`{"properties":{"id":{"$ref":"#/$defs/x"}},"$defs":{"x":{"$ref":"#/$defs/x"}}}`,
+		`{"properties":{"id":{"$ref":"#/$defs/x"}},"$defs":{"x":{"$ref":"#/$defs/y"},"y":{"allOf":[{"$ref":"#/$defs/x"}]}}}`,
+	} {
+		_, err := TemplateFromJSONSchema([]byte(schema))
+		assert.NotNil(t, err, schema)
+	}
+
+	template, err := TemplateFromJSONSchema([]byte(`{"properties":{"list":{"$ref":"#/$defs/list"}},"$defs":{"list":{"type":"array","items":{"$ref":"#/$defs/item"}},"item":{"$ref":"#/$defs/list"}}}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"list":"__template.__def_list","__def_list":["__template.__def_list"]}`)), formatJSON(template))
+}
+
+func TestTemplateFromJSONSchemaRootKeys(t *testing.T) {
+	// the definitions are the templates at root, which never apply to the keys out of the properties.
+	template, err := TemplateFromJSONSchema([]byte(`{
+		"properties": {"home": {"$ref": "#/$defs/address"}, "extra": {}},
+		"$defs": {"address": {"type": "object", "properties": {"zip": {"type": "string"}}}}
+	}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"home":"__template.__def_address","__def_address":{"zip":{"__function":"to_string","__null":"drop"}}}`)), formatJSON(template))
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+	formatted, err := provider.FormatJSONSchema([]byte(`{"home":{"zip":1},"extra":{"address":{"zip":2}},"address":{"zip":3},"__def_address":{"zip":4}}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"home":{"zip":"1"},"extra":{"address":{"zip":2}},"address":{"zip":3},"__def_address":{"zip":4}}`)), formatJSON(formatted))
+}
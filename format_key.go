@@ -1,17 +1,22 @@
 package normalizejson
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 )
 
 func JSONSchemaFormatKey(data []byte, options ...FormatOption) ([]byte, error) {
+	return JSONSchemaFormatKeyContext(context.Background(), data, options...)
+}
+
+func JSONSchemaFormatKeyContext(ctx context.Context, data []byte, options ...FormatOption) ([]byte, error) {
 	formatter := newFormatKeyImpl(options...)
-	return formatter.formatJSONSchema(data)
+	return formatter.formatJSONSchema(ctx, data)
 }
 
 type formatKeyImpl struct {
 	functionMap map[string]FormatFunc
+	config      FormatConfig
 }
 
 func newFormatKeyImpl(options ...FormatOption) *formatKeyImpl {
@@ -20,26 +25,27 @@ func newFormatKeyImpl(options ...FormatOption) *formatKeyImpl {
 	return fki
 }
 
-func (fki *formatKeyImpl) formatJSONSchema(data []byte) ([]byte, error) {
-	if len(fki.functionMap) == 0 {
+func (fki *formatKeyImpl) formatJSONSchema(ctx context.Context, data []byte) ([]byte, error) {
+	if len(fki.functionMap) == 0 && fki.config.keepsData() {
 		return data, nil
 	}
 
-	var item interface{}
-	if err := json.Unmarshal(data, &item); err != nil {
+	item, err := unmarshalJSON(data, fki.config)
+	if err != nil {
 		return data, err
 	}
 
-	formattedItem, err := fki.formatItem(item)
+	formattedItem, err := fki.formatItem(ctx, item)
 	if err != nil {
 		return data, err
 	}
 
-	return json.Marshal(formattedItem)
+	return marshalOutput(formattedItem, data, fki.config)
 }
 
 func (fki *formatKeyImpl) reset() {
 	fki.functionMap = make(map[string]FormatFunc)
+	fki.config = FormatConfig{}
 }
 
 func (fki *formatKeyImpl) addOptions(options ...FormatOption) {
@@ -48,24 +54,33 @@ func (fki *formatKeyImpl) addOptions(options ...FormatOption) {
 	}
 
 	for _, option := range options {
+		if option.FunctionType == FormatFuncFormatConfig {
+			fki.config = option.Config
+			continue
+		}
 		fki.functionMap[option.FunctionName] = option.FormatFunction
 	}
 }
 
-func (fki *formatKeyImpl) formatItem(item interface{}) (interface{}, error) {
+func (fki *formatKeyImpl) formatItem(ctx context.Context, item interface{}) (interface{}, error) {
+	// stop formatting once the context is cancelled.
+	if err := ctx.Err(); err != nil {
+		return item, err
+	}
+
 	switch v := item.(type) {
 	case []interface{}:
-		return fki.formatItemList(v)
+		return fki.formatItemList(ctx, v)
 	case map[string]interface{}:
-		return fki.formatItemMap(v)
+		return fki.formatItemMap(ctx, v)
 	default:
 		return item, nil
 	}
 }
 
-func (fki *formatKeyImpl) formatItemList(itemList []interface{}) ([]interface{}, error) {
+func (fki *formatKeyImpl) formatItemList(ctx context.Context, itemList []interface{}) ([]interface{}, error) {
 	for index, item := range itemList {
-		formattedItem, err := fki.formatItem(item)
+		formattedItem, err := fki.formatItem(ctx, item)
 		if err != nil {
 			return itemList, err
 		}
@@ -74,9 +89,11 @@ func (fki *formatKeyImpl) formatItemList(itemList []interface{}) ([]interface{},
 	return itemList, nil
 }
 
-func (fki *formatKeyImpl) formatItemMap(itemMap map[string]interface{}) (map[string]interface{}, error) {
+func (fki *formatKeyImpl) formatItemMap(ctx context.Context, itemMap map[string]interface{}) (map[string]interface{}, error) {
+	// the formatted entries are put into a new map, so that the renamed keys are never visited again.
+	formattedMap := make(map[string]interface{}, len(itemMap))
 	for key, item := range itemMap {
-		formattedItem, err := fki.formatItem(item)
+		formattedItem, err := fki.formatItem(ctx, item)
 		if err != nil {
 			return itemMap, err
 		}
@@ -86,11 +103,10 @@ func (fki *formatKeyImpl) formatItemMap(itemMap map[string]interface{}) (map[str
 			return itemMap, err
 		}
 
-		delete(itemMap, key)
-		itemMap[formattedKey] = formattedItem
+		formattedMap[formattedKey] = formattedItem
 	}
 
-	return itemMap, nil
+	return formattedMap, nil
 }
 
 func (fki *formatKeyImpl) formatKey(key string) (string, error) {
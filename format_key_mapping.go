@@ -0,0 +1,241 @@
+package normalizejson
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	FormatMapKey = "map_key"
+)
+
+const (
+	keyMappingCacheSize = 4096 // the resolved keys cached, which is cleared once full.
+)
+
+type KeyMappingConfig struct {
+	CaseInsensitive bool // match the keys in mapping table ignoring case.
+	FuzzyDistance   int  // match the closest key within the edit distance, 0 means exact match only.
+	ErrorOnUnmapped bool // return error instead of retaining the key which cannot be mapped.
+}
+
+// KeyMapping renames JSON keys with a mapping table, such as "usrNm" to "user_name".
+// The keys which cannot be mapped are retained and recorded, which can be read by UnmappedKeys. The recorded keys
+// grow with the distinct unmapped keys until ResetUnmappedKeys, and the resolved keys are cached in a bounded cache.
+type KeyMapping struct {
+	config      KeyMappingConfig
+	table       map[string]string
+	targets     map[string]struct{}
+	resolvedMap map[string]string
+	unmappedMap map[string]struct{}
+	mutex       sync.Mutex
+}
+
+// NewKeyMapping creates the mapping from table, the source keys differing only in case should take the same target
+// with CaseInsensitive.
+func NewKeyMapping(table map[string]string, config KeyMappingConfig) (*KeyMapping, error) {
+	km := &KeyMapping{
+		config:      config,
+		table:       make(map[string]string, len(table)),
+		targets:     make(map[string]struct{}, len(table)),
+		resolvedMap: make(map[string]string),
+		unmappedMap: make(map[string]struct{}),
+	}
+
+	for source, target := range table {
+		folded := km.fold(source)
+		if exist, ok := km.table[folded]; ok && exist != target {
+			return nil, fmt.Errorf("the key %s is mapped to both %s and %s ignoring case", source, exist, target)
+		}
+		km.table[folded] = target
+		km.targets[target] = struct{}{}
+	}
+	return km, nil
+}
+
+// NewKeyMappingFromFile reads the mapping table from a JSON file (an object of source key to target key)
+// or a CSV file (rows of source key and target key, lines starting with '#' are ignored).
+func NewKeyMappingFromFile(path string, config KeyMappingConfig) (*KeyMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return NewKeyMappingFromJSON(file, config)
+	case ".csv":
+		return NewKeyMappingFromCSV(file, config)
+	default:
+		return nil, fmt.Errorf("unsupported key mapping file: %s", path)
+	}
+}
+
+func NewKeyMappingFromJSON(reader io.Reader, config KeyMappingConfig) (*KeyMapping, error) {
+	table := make(map[string]string)
+	if err := json.NewDecoder(reader).Decode(&table); err != nil {
+		return nil, fmt.Errorf("read key mapping failed: %s", err)
+	}
+	return NewKeyMapping(table, config)
+}
+
+func NewKeyMappingFromCSV(reader io.Reader, config KeyMappingConfig) (*KeyMapping, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read key mapping failed: %s", err)
+	}
+
+	table := make(map[string]string, len(records))
+	for _, record := range records {
+		table[record[0]] = record[1]
+	}
+	return NewKeyMapping(table, config)
+}
+
+func KeyMappingOption(funcName string, km *KeyMapping) FormatOption {
+	return FormatKeyOption(funcName, km.FormatKey)
+}
+
+func (km *KeyMapping) FormatKey(item interface{}) (interface{}, error) {
+	key, ok := item.(string)
+	if !ok {
+		return item, nil
+	}
+
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	if resolved, ok := km.resolvedMap[key]; ok {
+		return resolved, nil
+	}
+
+	resolved, ok := km.resolve(key)
+	if !ok {
+		if km.config.ErrorOnUnmapped {
+			return key, fmt.Errorf("unmapped key %s", key)
+		}
+		km.unmappedMap[key] = struct{}{}
+		return key, nil
+	}
+
+	if len(km.resolvedMap) >= keyMappingCacheSize {
+		km.resolvedMap = make(map[string]string)
+	}
+	km.resolvedMap[key] = resolved
+	return resolved, nil
+}
+
+// UnmappedKeys returns the keys which cannot be mapped since created or the last ResetUnmappedKeys, which are sorted.
+func (km *KeyMapping) UnmappedKeys() []string {
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	keys := make([]string, 0, len(km.unmappedMap))
+	for key := range km.unmappedMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (km *KeyMapping) ResetUnmappedKeys() {
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	km.unmappedMap = make(map[string]struct{})
+}
+
+func (km *KeyMapping) resolve(key string) (string, bool) {
+	if target, ok := km.table[km.fold(key)]; ok {
+		return target, true
+	}
+
+	// the key has been mapped already.
+	if _, ok := km.targets[key]; ok {
+		return key, true
+	}
+
+	if km.config.FuzzyDistance <= 0 {
+		return key, false
+	}
+
+	// take the closest source key, the ambiguous ones are regarded as unmapped.
+	folded := km.fold(key)
+	bestDistance, bestTarget, ambiguous := km.config.FuzzyDistance+1, "", false
+	for source, target := range km.table {
+		distance := editDistance(folded, source)
+		switch {
+		case distance < bestDistance:
+			bestDistance, bestTarget, ambiguous = distance, target, false
+		case distance == bestDistance && target != bestTarget:
+			ambiguous = true
+		}
+	}
+
+	if bestDistance > km.config.FuzzyDistance || ambiguous {
+		return key, false
+	}
+	return bestTarget, true
+}
+
+func (km *KeyMapping) fold(key string) string {
+	if km.config.CaseInsensitive {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// editDistance returns the Levenshtein distance between two strings in runes.
+func editDistance(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return utf8.RuneCountInString(b)
+	}
+	if len(rb) == 0 {
+		return utf8.RuneCountInString(a)
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,116 @@
+package normalizejson
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyMapping(t *testing.T) {
+	dir := "key_mapping"
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	config := KeyMappingConfig{CaseInsensitive: true, FuzzyDistance: 2}
+	for _, file := range []string{"mapping.json", "mapping.csv"} {
+		km, err := NewKeyMappingFromFile("testdata/"+dir+"/"+file, config)
+		if err != nil {
+			panic(err)
+		}
+
+		formatted, err := JSONSchemaFormatKey(source, KeyMappingOption(FormatMapKey, km))
+		if err != nil {
+			panic(err)
+		}
+
+		assert.Equal(t, formatJSON(result), formatJSON(formatted))
+		assert.Equal(t, []string{"currency"}, km.UnmappedKeys())
+	}
+}
+
+func TestKeyMappingUnmapped(t *testing.T) {
+	dir := "key_mapping"
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	km, err := NewKeyMappingFromFile("testdata/"+dir+"/mapping.json", KeyMappingConfig{})
+	if err != nil {
+		panic(err)
+	}
+
+	provider := NewFormatKeyProvider(KeyMappingOption(FormatMapKey, km))
+	if _, err = provider.FormatJSONSchema(source); err != nil {
+		panic(err)
+	}
+	assert.Equal(t, []string{"ACCTLIST", "acctNum", "currency"}, km.UnmappedKeys())
+
+	km.ResetUnmappedKeys()
+	assert.Empty(t, km.UnmappedKeys())
+
+	strict, err := NewKeyMapping(map[string]string{"usrNm": "user_name"}, KeyMappingConfig{ErrorOnUnmapped: true})
+	if err != nil {
+		panic(err)
+	}
+	_, err = JSONSchemaFormatKey(source, KeyMappingOption(FormatMapKey, strict))
+	assert.NotNil(t, err)
+}
+
+func TestKeyMappingCaseCollision(t *testing.T) {
+	// the keys differing only in case cannot take different targets ignoring case.
+	table := map[string]string{"usrNm": "user_name", "USRNM": "user_nickname"}
+	_, err := NewKeyMapping(table, KeyMappingConfig{CaseInsensitive: true})
+	assert.NotNil(t, err)
+
+	km, err := NewKeyMapping(table, KeyMappingConfig{})
+	if err != nil {
+		panic(err)
+	}
+	formatted, err := JSONSchemaFormatKey([]byte(`{"usrNm":1,"USRNM":2}`), KeyMappingOption(FormatMapKey, km))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"user_name":1,"user_nickname":2}`)), formatJSON(formatted))
+
+	km, err = NewKeyMapping(map[string]string{"usrNm": "user_name", "USRNM": "user_name"}, KeyMappingConfig{CaseInsensitive: true})
+	if err != nil {
+		panic(err)
+	}
+	formatted, err = JSONSchemaFormatKey([]byte(`{"UsrNm":1}`), KeyMappingOption(FormatMapKey, km))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"user_name":1}`, string(formatted))
+}
+
+func TestKeyMappingCache(t *testing.T) {
+	table := make(map[string]string)
+	for index := 0; index < keyMappingCacheSize+10; index++ {
+		table["key"+strconv.Itoa(index)] = "target_" + strconv.Itoa(index)
+	}
+	km, err := NewKeyMapping(table, KeyMappingConfig{})
+	if err != nil {
+		panic(err)
+	}
+
+	// the resolved keys are cached in a bounded cache.
+	for index := 0; index < keyMappingCacheSize+10; index++ {
+		key, err := km.FormatKey("key" + strconv.Itoa(index))
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, "target_"+strconv.Itoa(index), key)
+	}
+	assert.LessOrEqual(t, len(km.resolvedMap), keyMappingCacheSize)
+}
@@ -0,0 +1,475 @@
+package normalizejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	lenientMaxDepth = 10000
+)
+
+// JSONSyntaxError is the syntax error of the lenient JSON, with the position where it occurs.
+type JSONSyntaxError struct {
+	Line   int // the line number, starting from 1.
+	Column int // the column number in characters, starting from 1.
+	Msg    string
+}
+
+func (e *JSONSyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ParseLenientJSON converts the JSON5 or JSONC document to the standard JSON, which takes the comments, trailing
+// commas, single-quoted strings, unquoted keys, hexadecimal numbers and the numbers like ".5", "5." and "+5".
+// Infinity and NaN are rejected since JSON cannot represent them. The errors are JSONSyntaxError.
+func ParseLenientJSON(data []byte) ([]byte, error) {
+	p := &lenientParser{data: data}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.data) {
+		return nil, p.errorf(p.pos, "unexpected end of JSON input")
+	}
+	if err := p.parseValue(0); err != nil {
+		return nil, err
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf(p.pos, "invalid character %q after top-level value", p.peekRune())
+	}
+	return p.buf.Bytes(), nil
+}
+
+type lenientParser struct {
+	data []byte
+	pos  int
+	buf  bytes.Buffer
+}
+
+// errorf creates the error at the position pos of data.
+func (p *lenientParser) errorf(pos int, format string, args ...interface{}) error {
+	line, column := 1, 1
+	for index := 0; index < pos && index < len(p.data); {
+		r, size := utf8.DecodeRune(p.data[index:])
+		index += size
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &JSONSyntaxError{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *lenientParser) peekRune() rune {
+	r, _ := utf8.DecodeRune(p.data[p.pos:])
+	return r
+}
+
+// skipSpace skips the white spaces and comments.
+func (p *lenientParser) skipSpace() error {
+	for p.pos < len(p.data) {
+		r, size := utf8.DecodeRune(p.data[p.pos:])
+		switch {
+		case isLenientSpace(r):
+			p.pos += size
+		case bytes.HasPrefix(p.data[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.data[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.data)
+			} else {
+				p.pos += end + 1
+			}
+		case bytes.HasPrefix(p.data[p.pos:], []byte("/*")):
+			end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				return p.errorf(p.pos, "unterminated comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isLenientSpace(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', ' ', '\u00a0', '\u2028', '\u2029', '\ufeff':
+		return true
+	default:
+		return unicode.Is(unicode.Zs, r)
+	}
+}
+
+func (p *lenientParser) parseValue(depth int) error {
+	if depth > lenientMaxDepth {
+		return p.errorf(p.pos, "exceeded max depth")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.parseObject(depth)
+	case c == '[':
+		return p.parseArray(depth)
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	default:
+		start := p.pos
+		name := p.readIdentifier()
+		switch name {
+		case "true", "false", "null":
+			p.buf.WriteString(name)
+			return nil
+		case "Infinity", "NaN":
+			return p.errorf(start, "unsupported number %s", name)
+		case "":
+			return p.errorf(start, "invalid character %q looking for beginning of value", p.peekRune())
+		default:
+			return p.errorf(start, "invalid literal %s", name)
+		}
+	}
+}
+
+func (p *lenientParser) parseObject(depth int) error {
+	p.pos++
+	p.buf.WriteByte('{')
+	for count := 0; ; count++ {
+		if err := p.skipSpace(); err != nil {
+			return err
+		}
+		if p.pos >= len(p.data) {
+			return p.errorf(p.pos, "unexpected end of JSON input")
+		}
+		if p.data[p.pos] == '}' {
+			p.pos++
+			p.buf.WriteByte('}')
+			return nil
+		}
+		if count > 0 {
+			p.buf.WriteByte(',')
+		}
+
+		// the key is a string or an identifier.
+		if c := p.data[p.pos]; c == '"' || c == '\'' {
+			if err := p.parseString(); err != nil {
+				return err
+			}
+		} else {
+			start := p.pos
+			key := p.readIdentifier()
+			if key == "" {
+				return p.errorf(start, "invalid character %q looking for beginning of object key", p.peekRune())
+			}
+			p.writeString(key)
+		}
+
+		if err := p.skipSpace(); err != nil {
+			return err
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return p.errorf(p.pos, "expected ':' after object key")
+		}
+		p.pos++
+		p.buf.WriteByte(':')
+
+		if err := p.parseElement(depth); err != nil {
+			return err
+		}
+		if done, err := p.parseSeparator('}'); done || err != nil {
+			return err
+		}
+	}
+}
+
+func (p *lenientParser) parseArray(depth int) error {
+	p.pos++
+	p.buf.WriteByte('[')
+	for count := 0; ; count++ {
+		if err := p.skipSpace(); err != nil {
+			return err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			p.buf.WriteByte(']')
+			return nil
+		}
+		if count > 0 {
+			p.buf.WriteByte(',')
+		}
+
+		if err := p.parseElement(depth); err != nil {
+			return err
+		}
+		if done, err := p.parseSeparator(']'); done || err != nil {
+			return err
+		}
+	}
+}
+
+// parseElement parses the value in object or array.
+func (p *lenientParser) parseElement(depth int) error {
+	if err := p.skipSpace(); err != nil {
+		return err
+	}
+	if p.pos >= len(p.data) {
+		return p.errorf(p.pos, "unexpected end of JSON input")
+	}
+	return p.parseValue(depth + 1)
+}
+
+// parseSeparator parses the comma after a value, or the end of object or array, which may follow a trailing comma.
+func (p *lenientParser) parseSeparator(end byte) (bool, error) {
+	if err := p.skipSpace(); err != nil {
+		return false, err
+	}
+	if p.pos >= len(p.data) {
+		return false, p.errorf(p.pos, "unexpected end of JSON input")
+	}
+
+	switch p.data[p.pos] {
+	case ',':
+		p.pos++
+		return false, nil
+	case end:
+		p.pos++
+		p.buf.WriteByte(end)
+		return true, nil
+	default:
+		return false, p.errorf(p.pos, "invalid character %q after element, expected ',' or %q", p.peekRune(), end)
+	}
+}
+
+// readIdentifier reads the identifier like the unquoted keys, which is empty if there is not.
+func (p *lenientParser) readIdentifier() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		r, size := utf8.DecodeRune(p.data[p.pos:])
+		if !(r == '_' || r == '$' || unicode.IsLetter(r) || (p.pos > start && (unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Pc, r)))) {
+			break
+		}
+		p.pos += size
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *lenientParser) parseString() error {
+	start := p.pos
+	quote := p.data[p.pos]
+	p.pos++
+
+	var builder strings.Builder
+	for {
+		if p.pos >= len(p.data) {
+			return p.errorf(start, "unterminated string")
+		}
+
+		r, size := utf8.DecodeRune(p.data[p.pos:])
+		switch {
+		case r == rune(quote):
+			p.pos += size
+			p.writeString(builder.String())
+			return nil
+		case r == '\n' || r == '\r':
+			return p.errorf(p.pos, "unterminated string")
+		case r == '\\':
+			if err := p.parseEscape(&builder); err != nil {
+				return err
+			}
+		default:
+			p.pos += size
+			builder.WriteRune(r)
+		}
+	}
+}
+
+// parseEscape parses the escape sequence in string, including the ones of JSON5 like \x41, \0 and line continuations.
+func (p *lenientParser) parseEscape(builder *strings.Builder) error {
+	start := p.pos
+	p.pos++
+	if p.pos >= len(p.data) {
+		return p.errorf(start, "unterminated string")
+	}
+
+	r, size := utf8.DecodeRune(p.data[p.pos:])
+	p.pos += size
+	switch r {
+	case 'b':
+		builder.WriteByte('\b')
+	case 'f':
+		builder.WriteByte('\f')
+	case 'n':
+		builder.WriteByte('\n')
+	case 'r':
+		builder.WriteByte('\r')
+	case 't':
+		builder.WriteByte('\t')
+	case 'v':
+		builder.WriteByte('\v')
+	case '0':
+		if p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+			return p.errorf(start, "invalid escape sequence")
+		}
+		builder.WriteByte(0)
+	case 'x':
+		code, ok := p.readHex(2)
+		if !ok {
+			return p.errorf(start, "invalid escape sequence")
+		}
+		builder.WriteRune(rune(code))
+	case 'u':
+		code, ok := p.readHex(4)
+		if !ok {
+			return p.errorf(start, "invalid escape sequence")
+		}
+		// combine the surrogate pair.
+		if code >= 0xd800 && code < 0xdc00 && bytes.HasPrefix(p.data[p.pos:], []byte(`\u`)) {
+			pos := p.pos
+			p.pos += 2
+			if low, ok := p.readHex(4); ok && low >= 0xdc00 && low < 0xe000 {
+				builder.WriteRune((rune(code)-0xd800)<<10 + (rune(low) - 0xdc00) + 0x10000)
+				return nil
+			}
+			p.pos = pos
+		}
+		builder.WriteRune(rune(code))
+	case '\r':
+		// the line continuation.
+		if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+			p.pos++
+		}
+	case '\n', '\u2028', '\u2029':
+		// the line continuation.
+	default:
+		if r >= '1' && r <= '9' {
+			return p.errorf(start, "invalid escape sequence")
+		}
+		builder.WriteRune(r)
+	}
+	return nil
+}
+
+func (p *lenientParser) readHex(n int) (int, bool) {
+	if p.pos+n > len(p.data) {
+		return 0, false
+	}
+
+	code := 0
+	for _, c := range p.data[p.pos : p.pos+n] {
+		switch {
+		case c >= '0' && c <= '9':
+			code = code*16 + int(c-'0')
+		case c >= 'a' && c <= 'f':
+			code = code*16 + int(c-'a'+10)
+		case c >= 'A' && c <= 'F':
+			code = code*16 + int(c-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+	p.pos += n
+	return code, true
+}
+
+// writeString writes the string as a JSON string without escaping HTML.
+func (p *lenientParser) writeString(str string) {
+	encoder := json.NewEncoder(&p.buf)
+	encoder.SetEscapeHTML(false)
+	// the string is always encoded.
+	_ = encoder.Encode(str)
+	p.buf.Truncate(p.buf.Len() - 1)
+}
+
+func (p *lenientParser) parseNumber() error {
+	start := p.pos
+	negative := false
+	if c := p.data[p.pos]; c == '-' || c == '+' {
+		negative = c == '-'
+		p.pos++
+	}
+
+	if name := p.readIdentifier(); name != "" {
+		if name == "Infinity" || name == "NaN" {
+			return p.errorf(start, "unsupported number %s", string(p.data[start:p.pos]))
+		}
+		return p.errorf(start, "invalid number %s", string(p.data[start:p.pos]))
+	}
+
+	// the hexadecimal integer.
+	if bytes.HasPrefix(p.data[p.pos:], []byte("0x")) || bytes.HasPrefix(p.data[p.pos:], []byte("0X")) {
+		p.pos += 2
+		digits := p.readDigits(true)
+		n, ok := new(big.Int).SetString(digits, 16)
+		if !ok {
+			return p.errorf(start, "invalid number %s", string(p.data[start:p.pos]))
+		}
+		if negative {
+			n.Neg(n)
+		}
+		p.buf.WriteString(n.String())
+		return nil
+	}
+
+	intPart := p.readDigits(false)
+	var fracPart string
+	hasPoint := p.pos < len(p.data) && p.data[p.pos] == '.'
+	if hasPoint {
+		p.pos++
+		fracPart = p.readDigits(false)
+	}
+	if intPart == "" && fracPart == "" {
+		return p.errorf(start, "invalid number %s", string(p.data[start:p.pos]))
+	}
+	if len(intPart) > 1 && intPart[0] == '0' {
+		return p.errorf(start, "invalid number %s with leading zero", string(p.data[start:p.pos]))
+	}
+
+	var exponent string
+	if p.pos < len(p.data) && (p.data[p.pos] == 'e' || p.data[p.pos] == 'E') {
+		expStart := p.pos
+		p.pos++
+		if p.pos < len(p.data) && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+			p.pos++
+		}
+		if p.readDigits(false) == "" {
+			return p.errorf(start, "invalid number %s", string(p.data[start:p.pos]))
+		}
+		exponent = string(p.data[expStart:p.pos])
+	}
+
+	if negative {
+		p.buf.WriteByte('-')
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	p.buf.WriteString(intPart)
+	if fracPart != "" {
+		p.buf.WriteString("." + fracPart)
+	}
+	p.buf.WriteString(exponent)
+	return nil
+}
+
+func (p *lenientParser) readDigits(hex bool) string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if !(c >= '0' && c <= '9') && !(hex && ((c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'))) {
+			break
+		}
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
@@ -0,0 +1,88 @@
+package normalizejson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLenient(t *testing.T) {
+	dir := "format_lenient"
+	template, err := readTestData(dir, "config.json5")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json5")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	// the template is strict without the lenient config.
+	_, err = NewFormatProvider(template, DefaultFormatDataOptions...)
+	assert.NotNil(t, err)
+
+	// the config option is taken before the template.
+	options := append([]FormatOption{FormatConfigOption(FormatConfig{Lenient: true})}, DefaultFormatDataOptions...)
+	for _, create := range []func([]byte, ...FormatOption) (FormatProvider, error){NewFormatSchemaProvider, NewFormatDataProvider} {
+		provider, err := create(template, options...)
+		if err != nil {
+			panic(err)
+		}
+
+		formatted, err := provider.FormatJSONSchema(source)
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+		// the syntax errors of data keep their positions.
+		_, err = provider.FormatJSONSchema([]byte("{\n  a: 'x\n}"))
+		var syntaxErr *JSONSyntaxError
+		assert.True(t, errors.As(err, &syntaxErr))
+		assert.Equal(t, 2, syntaxErr.Line)
+	}
+
+	formatted, err := JSONSchemaFormat(source, template, options...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestParseLenientJSON(t *testing.T) {
+	for source, expected := range map[string]string{
+		`{a: 1, 'b': "x", c: [1, 2,],}`:        `{"a":1,"b":"x","c":[1,2]}`,
+		"// comment\n[.5, 5., +1, -0x1F, 1e3]": `[0.5,5,1,-31,1e3]`,
+		`'\x41é😀 "<tag>"'`:                     `"Aé😀 \"<tag>\""`,
+		`{$id: null, _ok: true /* flag */}`:    `{"$id":null,"_ok":true}`,
+	} {
+		converted, err := ParseLenientJSON([]byte(source))
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, expected, string(converted))
+	}
+
+	for source, expected := range map[string]string{
+		"{\n  a: 1,,\n}": "line 2, column 8: invalid character ',' looking for beginning of object key",
+		"{\n  a: 'x\n}":  "line 2, column 8: unterminated string",
+		"[1\n 2]":        "line 2, column 2: invalid character '2' after element, expected ',' or ']'",
+		"{a: NaN}":       "line 1, column 5: unsupported number NaN",
+		"{a: 01}":        "line 1, column 5: invalid number 01 with leading zero",
+		"/* comment":     "line 1, column 1: unterminated comment",
+		"{a: 1}\n{b: 2}": "line 2, column 1: invalid character '{' after top-level value",
+		"{a: 1, b: true": "line 1, column 15: unexpected end of JSON input",
+	} {
+		_, err := ParseLenientJSON([]byte(source))
+		var syntaxErr *JSONSyntaxError
+		assert.True(t, errors.As(err, &syntaxErr))
+		assert.Equal(t, expected, err.Error())
+	}
+}
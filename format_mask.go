@@ -0,0 +1,213 @@
+package normalizejson
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	FormatRedact     = "redact"
+	FormatMask       = "mask"
+	FormatMaskEmail  = "mask_email"
+	FormatMaskFormat = "mask_format"
+	FormatHMACSHA256 = "hmac_sha256"
+)
+
+const (
+	RedactedValue = "[REDACTED]"
+)
+
+// MaskFormatDataOptions are the data-options to redact the sensitive values, such as emails, phone numbers,
+// card numbers and tokens. The numbers are masked as strings.
+//
+// The parameters in function leaf of template:
+//
+//   - "redact": "replacement" is the value to replace with, RedactedValue by default.
+//   - "mask": masks all the characters except the first "keep_first" (0 by default) and the last "keep_last"
+//     (4 by default) ones with "mask_char" ("*" by default).
+//   - "mask_email": masks the local part of email except the first character, "mask_char" is the same as "mask".
+//   - "mask_format": masks the letters and digits except the last "keep_last" (0 by default) ones, and retains the
+//     separators, e.g. "4111-1111-1111-1234" to "****-****-****-1234". "mask_char" is the same as "mask".
+//   - "hmac_sha256": the keyed pseudonymization in hex, the key is read from the environment variable "key_env", or
+//     "key" (not recommended in template). Use FormatHMACSHA256Option to take the key in Go.
+//
+// E.g. {"__function":"mask","keep_last":4}.
+var MaskFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatRedact, BuildFormatDataRedact),
+	FormatDataBuilderOption(FormatMask, BuildFormatDataMask),
+	FormatDataBuilderOption(FormatMaskEmail, BuildFormatDataMaskEmail),
+	FormatDataBuilderOption(FormatMaskFormat, BuildFormatDataMaskFormat),
+	FormatDataBuilderOption(FormatHMACSHA256, BuildFormatDataHMACSHA256),
+}
+
+func BuildFormatDataRedact(params map[string]interface{}) (FormatFunc, error) {
+	replacement, ok := params["replacement"]
+	if !ok {
+		replacement = RedactedValue
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		if item == nil {
+			return nil, nil
+		}
+		return replacement, nil
+	}, nil
+}
+
+func BuildFormatDataMask(params map[string]interface{}) (FormatFunc, error) {
+	keepFirst, err := paramInt(params, "keep_first", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	keepLast, err := paramInt(params, "keep_last", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	maskChar, err := paramMaskChar(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatMaskable(item, func(str string) string {
+			return maskString(str, keepFirst, keepLast, maskChar)
+		})
+	}, nil
+}
+
+func BuildFormatDataMaskEmail(params map[string]interface{}) (FormatFunc, error) {
+	maskChar, err := paramMaskChar(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatMaskable(item, func(str string) string {
+			at := strings.LastIndex(str, "@")
+			if at < 0 {
+				return maskString(str, 0, 0, maskChar)
+			}
+			return maskString(str[:at], 1, 0, maskChar) + str[at:]
+		})
+	}, nil
+}
+
+func BuildFormatDataMaskFormat(params map[string]interface{}) (FormatFunc, error) {
+	keepLast, err := paramInt(params, "keep_last", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	maskChar, err := paramMaskChar(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatMaskable(item, func(str string) string {
+			runes := []rune(str)
+			kept := 0
+			for index := len(runes) - 1; index >= 0; index-- {
+				if !unicode.IsLetter(runes[index]) && !unicode.IsDigit(runes[index]) {
+					continue
+				}
+				if kept < keepLast {
+					kept++
+					continue
+				}
+				runes[index] = maskChar
+			}
+			return string(runes)
+		})
+	}, nil
+}
+
+func BuildFormatDataHMACSHA256(params map[string]interface{}) (FormatFunc, error) {
+	keyEnv, err := paramString(params, "key_env", "")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := paramString(params, "key", "")
+	if err != nil {
+		return nil, err
+	}
+
+	if keyEnv != "" {
+		key = os.Getenv(keyEnv)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("no key for %s", FormatHMACSHA256)
+	}
+	return formatDataHMACSHA256([]byte(key)), nil
+}
+
+// FormatHMACSHA256Option creates a data-option named funcName for the keyed pseudonymization with HMAC-SHA256.
+func FormatHMACSHA256Option(funcName string, key []byte) FormatOption {
+	return FormatDataOption(funcName, formatDataHMACSHA256(key))
+}
+
+func formatDataHMACSHA256(key []byte) FormatFunc {
+	return func(item interface{}) (interface{}, error) {
+		return formatMaskable(item, func(str string) string {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(str))
+			return hex.EncodeToString(mac.Sum(nil))
+		})
+	}
+}
+
+func paramMaskChar(params map[string]interface{}) (rune, error) {
+	maskChar, err := paramString(params, "mask_char", "*")
+	if err != nil {
+		return 0, err
+	}
+	if utf8.RuneCountInString(maskChar) != 1 {
+		return 0, fmt.Errorf("illegal parameter mask_char: %s", maskChar)
+	}
+	r, _ := utf8.DecodeRuneInString(maskChar)
+	return r, nil
+}
+
+// formatMaskable masks the strings and numbers, null is retained and the other values are regarded as errors, so
+// that no value is left unmasked silently.
+func formatMaskable(item interface{}, f func(str string) string) (interface{}, error) {
+	switch item.(type) {
+	case nil:
+		return nil, nil
+	case bool, map[string]interface{}, []interface{}:
+		return item, fmt.Errorf("unable to mask %v of type %T", item, item)
+	}
+
+	str, err := cast.ToStringE(item)
+	if err != nil {
+		return item, fmt.Errorf("unable to mask %v of type %T", item, item)
+	}
+	return f(str), nil
+}
+
+// maskString masks the string except the first keepFirst and the last keepLast runes,
+// and masks the whole string if nothing would be masked.
+func maskString(str string, keepFirst, keepLast int, maskChar rune) string {
+	runes := []rune(str)
+	if keepFirst+keepLast >= len(runes) {
+		keepFirst, keepLast = 0, 0
+	}
+	for index := range runes {
+		if index < keepFirst || index >= len(runes)-keepLast {
+			continue
+		}
+		runes[index] = maskChar
+	}
+	return string(runes)
+}
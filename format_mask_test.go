@@ -0,0 +1,106 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMask(t *testing.T) {
+	dir := "format_mask"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	options := append(MaskFormatDataOptions, FormatHMACSHA256Option("pseudonymize", []byte("secret")))
+	formatted, err := JSONSchemaFormat(source, template, options...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestFormatMaskHMACKey(t *testing.T) {
+	t.Setenv("NORMALIZEJSON_HMAC_KEY", "secret")
+
+	template := []byte(`{"user_id":{"__function":"hmac_sha256","key_env":"NORMALIZEJSON_HMAC_KEY"}}`)
+	formatted, err := JSONSchemaFormat([]byte(`{"user_id":"alice"}`), template, MaskFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON([]byte(`{"user_id":"4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8"}`)), formatJSON(formatted))
+
+	_, err = JSONSchemaFormat([]byte(`{"user_id":"alice"}`), []byte(`{"user_id":{"__function":"hmac_sha256"}}`), MaskFormatDataOptions...)
+	assert.NotNil(t, err)
+
+	_, err = JSONSchemaFormat([]byte(`{"user_id":"alice"}`), []byte(`{"__redact":["("]}`))
+	assert.NotNil(t, err)
+	// the values which are not scalar cannot be masked.
+	for _, source := range []string{`{"card":{"number":"4111111111111111"}}`, `{"card":["4111111111111111"]}`, `{"card":true}`} {
+		_, err = JSONSchemaFormat([]byte(source), []byte(`{"card":{"__function":"mask"}}`), MaskFormatDataOptions...)
+		assert.NotNil(t, err, source)
+	}
+}
+
+func TestFormatMaskRedactProviders(t *testing.T) {
+	dir := "format_mask"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	// the "__redact" directive works in both the schema and data providers.
+	options := append(MaskFormatDataOptions, FormatHMACSHA256Option("pseudonymize", []byte("secret")))
+	for _, create := range []func([]byte, ...FormatOption) (FormatProvider, error){NewFormatSchemaProvider, NewFormatDataProvider} {
+		provider, err := create(template, options...)
+		if err != nil {
+			panic(err)
+		}
+
+		formatted, err := provider.FormatJSONSchema(source)
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+		formatted, err = provider.FormatJSONSchema([]byte(`{"orders":[{"password":"123456","items":[{"token":"abc"}]}]}`))
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, formatJSON([]byte(`{"orders":[{"password":"[REDACTED]","items":[{"token":"[REDACTED]"}]}]}`)), formatJSON(formatted))
+
+		// the directive in nested template applies to the object it describes.
+		provider, err = create([]byte(`{"customer":{"__redact":"^secret$","name":"to_string"}}`), DefaultFormatDataOptions...)
+		if err != nil {
+			panic(err)
+		}
+		formatted, err = provider.FormatJSONSchema([]byte(`{"secret":1,"customer":{"name":1,"secret":2,"profile":{"secret":3}}}`))
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, formatJSON([]byte(`{"secret":1,"customer":{"name":"1","secret":"[REDACTED]","profile":{"secret":"[REDACTED]"}}}`)), formatJSON(formatted))
+	}
+}
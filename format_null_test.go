@@ -0,0 +1,51 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatNullPolicy(t *testing.T) {
+	dir := "format_null"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, create := range []func([]byte) (FormatProvider, error){NewDefaultFormatSchemaProvider, NewDefaultFormatDataProvider} {
+		provider, err := create(template)
+		if err != nil {
+			panic(err)
+		}
+		provider.(ConfigurableProvider).UpdateConfig(FormatConfig{NullPolicy: NullPolicyPreserve, EmptyAsNull: true})
+
+		formatted, err := provider.FormatJSONSchema(source)
+		if err != nil {
+			panic(err)
+		}
+
+		assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+		provider.(ConfigurableProvider).UpdateConfig(FormatConfig{NullPolicy: NullPolicyError})
+		_, err = provider.FormatJSONSchema(source)
+		assert.NotNil(t, err)
+
+		provider.(ConfigurableProvider).UpdateConfig(FormatConfig{NullPolicy: "unknown"})
+		_, err = provider.FormatJSONSchema(source)
+		assert.NotNil(t, err)
+	}
+
+	_, err = NewDefaultFormatSchemaProvider([]byte(`{"id":{"__function":"to_int64","__null":"unknown"}}`))
+	assert.NotNil(t, err)
+}
@@ -10,17 +10,25 @@ import (
 type FormatFuncType string
 
 const (
-	FormatFuncFormatData FormatFuncType = "format_function_type_format_data" // default type
-	FormatFuncFormatKey                 = "format_function_type_format_key"
+	FormatFuncFormatData   FormatFuncType = "format_function_type_format_data" // default type
+	FormatFuncFormatKey                   = "format_function_type_format_key"
+	FormatFuncFormatConfig                = "format_function_type_format_config"
 )
 
 type FormatFunc func(item interface{}) (interface{}, error)
 
+// FormatFuncBuilder creates a FormatFunc with the parameters of a function leaf in template,
+// e.g. {"__function":"to_time","output":"epoch_ms"} invokes the builder of "to_time" with {"output":"epoch_ms"}.
+type FormatFuncBuilder func(params map[string]interface{}) (FormatFunc, error)
+
 type FormatOption struct {
-	FunctionType   FormatFuncType
-	FunctionName   string
-	FormatFunction FormatFunc
-	RetainKey      bool
+	FunctionType          FormatFuncType
+	FunctionName          string
+	FormatFunction        FormatFunc
+	FormatContextFunction FormatContextFunc
+	FormatBuilder         FormatFuncBuilder
+	RetainKey             bool
+	Config                FormatConfig // the config of provider taken by the option of FormatFuncFormatConfig.
 }
 
 func FormatDataOption(funcName string, formatFunc FormatFunc) FormatOption {
@@ -31,6 +39,27 @@ func FormatDataOption(funcName string, formatFunc FormatFunc) FormatOption {
 	}
 }
 
+// FormatDataBuilderOption creates a data-option with parameters.
+// The function built without parameters is taken when the template refers to it by name only,
+// so that a builder which requires parameters can only be used in function leaf.
+func FormatDataBuilderOption(funcName string, builder FormatFuncBuilder) FormatOption {
+	option := FormatDataOption(funcName, nil)
+	option.FormatBuilder = builder
+	if formatFunc, err := builder(nil); err == nil {
+		option.FormatFunction = formatFunc
+	}
+	return option
+}
+
+// FormatConfigOption creates an option which updates the config of provider as UpdateConfig does, the options are
+// added before the template in constructors, so that the template is parsed with the config, e.g. FormatConfig.Lenient.
+func FormatConfigOption(config FormatConfig) FormatOption {
+	return FormatOption{
+		FunctionType: FormatFuncFormatConfig,
+		Config:       config,
+	}
+}
+
 func FormatKeyOption(funcName string, formatFunc FormatFunc) FormatOption {
 	return FormatOption{
 		FunctionType:   FormatFuncFormatKey,
@@ -45,8 +74,10 @@ const (
 	FormatToString  = "to_string"
 	FormatToBool    = "to_bool"
 
-	FormatCamelToSnake = "camel_to_snake"
-	FormatSnakeToCamel = "snake_to_camel"
+	FormatCamelToSnake          = "camel_to_snake"
+	FormatSnakeToCamel          = "snake_to_camel"
+	FormatCamelToScreamingSnake = "camel_to_screaming_snake"
+	FormatScreamingSnakeToCamel = "screaming_snake_to_camel"
 )
 
 var DefaultFormatDataOptions = []FormatOption{
@@ -56,6 +87,22 @@ var DefaultFormatDataOptions = []FormatOption{
 	FormatDataOption(FormatToBool, FormatDataToBool),
 }
 
+var DefaultFormatKeyOptions = []FormatOption{
+	FormatKeyOption(FormatCamelToSnake, FormatKeyCamelToSnake),
+	FormatKeyOption(FormatSnakeToCamel, FormatKeySnakeToCamel),
+	FormatKeyOption(FormatCamelToScreamingSnake, FormatKeyCamelToScreamingSnake),
+	FormatKeyOption(FormatScreamingSnakeToCamel, FormatKeyScreamingSnakeToCamel),
+}
+
+func defaultFormatKeyFunc(funcName string) (FormatFunc, bool) {
+	for _, option := range DefaultFormatKeyOptions {
+		if option.FunctionName == funcName {
+			return option.FormatFunction, true
+		}
+	}
+	return nil, false
+}
+
 func FormatDataToString(item interface{}) (interface{}, error) {
 	return cast.ToStringE(item)
 }
@@ -93,3 +140,25 @@ func FormatKeySnakeToCamel(item interface{}) (interface{}, error) {
 	res := strings.Join(strList, "")
 	return firstToLower(res), nil
 }
+
+// FormatKeyCamelToScreamingSnake converts the keys to screaming-snake-case, e.g. "dbHost" to "DB_HOST",
+// the keys without lowercase letters are retained.
+func FormatKeyCamelToScreamingSnake(item interface{}) (interface{}, error) {
+	str, ok := item.(string)
+	if !ok {
+		return item, nil
+	}
+	if strings.ToUpper(str) == str {
+		return str, nil
+	}
+	return strings.ToUpper(regex.CamelCase.ReplaceAllString(str, `${1}_${2}`)), nil
+}
+
+// FormatKeyScreamingSnakeToCamel converts the keys from screaming-snake-case to camel-case, e.g. "DB_HOST" to "dbHost".
+func FormatKeyScreamingSnakeToCamel(item interface{}) (interface{}, error) {
+	str, ok := item.(string)
+	if !ok {
+		return item, nil
+	}
+	return FormatKeySnakeToCamel(strings.ToLower(str))
+}
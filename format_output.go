@@ -0,0 +1,404 @@
+package normalizejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	OutputCompact   = "compact"   // the compact JSON with sorted keys, as encoding/json does.
+	OutputPretty    = "pretty"    // the indented JSON with sorted keys.
+	OutputCanonical = "canonical" // the canonical JSON of RFC 8785 (JCS), e.g. to sign the normalized documents.
+	OutputOrdered   = "ordered"   // the JSON keeping the order of keys in source, the new keys follow in order, see writeOrderedJSON.
+)
+
+const (
+	DefaultOutputIndent = "  "
+)
+
+func isOutput(output string) bool {
+	switch output {
+	case "", OutputCompact, OutputPretty, OutputCanonical, OutputOrdered:
+		return true
+	default:
+		return false
+	}
+}
+
+// keepsData checks whether the data can be returned as it is if nothing is to be normalized.
+func (config FormatConfig) keepsData() bool {
+	return !config.Lenient && (config.Output == "" || config.Output == OutputCompact) && !config.DisableEscapeHTML && !config.TrailingNewline
+}
+
+// marshalOutput encodes the formatted item with the output options of config, source is the data before formatting.
+func marshalOutput(item interface{}, source []byte, config FormatConfig) ([]byte, error) {
+	var data []byte
+	var err error
+	switch config.Output {
+	case OutputPretty:
+		indent := config.Indent
+		if indent == "" {
+			indent = DefaultOutputIndent
+		}
+		data, err = encodeJSON(item, indent, !config.DisableEscapeHTML)
+	case OutputCanonical:
+		data, err = marshalCanonicalJSON(item)
+	case OutputOrdered:
+		data, err = marshalOrderedJSON(item, source, config)
+	default:
+		data, err = encodeJSON(item, "", !config.DisableEscapeHTML)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TrailingNewline {
+		data = append(data, '\n')
+	}
+	return data, nil
+}
+
+func encodeJSON(item interface{}, indent string, escapeHTML bool) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(escapeHTML)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(item); err != nil {
+		return nil, err
+	}
+	// remove the newline added by encoder.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// toGenericJSON converts the item to the generic JSON value, since the data functions may produce any type.
+func toGenericJSON(item interface{}) (interface{}, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalJSON(data, FormatConfig{UseNumber: true})
+}
+
+// CanonicalJSON converts the JSON document to the canonical JSON of RFC 8785 (JCS).
+func CanonicalJSON(data []byte) ([]byte, error) {
+	item, err := unmarshalJSON(data, FormatConfig{UseNumber: true})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	}
+	return marshalCanonicalJSON(item)
+}
+
+func marshalCanonicalJSON(item interface{}) ([]byte, error) {
+	generic, err := toGenericJSON(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = writeCanonicalJSON(&buf, generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonicalJSON(buf *bytes.Buffer, item interface{}) error {
+	switch v := item.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case string:
+		writeCanonicalString(buf, v)
+	case json.Number:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return fmt.Errorf("illegal number %s: %s", v, err)
+		}
+		str, err := formatES6Number(f)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(str)
+	case []interface{}:
+		buf.WriteByte('[')
+		for index, element := range v {
+			if index > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buf, element); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		// the keys are sorted by their UTF-16 code units.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		buf.WriteByte('{')
+		for index, key := range keys {
+			if index > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, key)
+			buf.WriteByte(':')
+			if err := writeCanonicalJSON(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+func lessUTF16(a string, b string) bool {
+	x, y := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for index := 0; index < len(x) && index < len(y); index++ {
+		if x[index] != y[index] {
+			return x[index] < y[index]
+		}
+	}
+	return len(x) < len(y)
+}
+
+// writeCanonicalString escapes only the quote, the backslash and the control characters.
+func writeCanonicalString(buf *bytes.Buffer, str string) {
+	buf.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// formatES6Number formats the number as ECMAScript does, which is required by RFC 8785.
+func formatES6Number(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("unsupported number %v", f)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+
+	// take the shortest digits and the exponent, such as "-1.5e+21".
+	str := strconv.FormatFloat(f, 'e', -1, 64)
+	var sign string
+	if str[0] == '-' {
+		sign, str = "-", str[1:]
+	}
+	index := strings.IndexByte(str, 'e')
+	digits := strings.Replace(str[:index], ".", "", 1)
+	exponent, err := strconv.Atoi(str[index+1:])
+	if err != nil {
+		return "", err
+	}
+
+	// the value is 0.digits * 10^n.
+	n, k := exponent+1, len(digits)
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k), nil
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:], nil
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits, nil
+	}
+
+	mantissa := digits[:1]
+	if k > 1 {
+		mantissa += "." + digits[1:]
+	}
+	if n-1 < 0 {
+		return sign + mantissa + "e-" + strconv.Itoa(1-n), nil
+	}
+	return sign + mantissa + "e+" + strconv.Itoa(n-1), nil
+}
+
+// orderedNode is the order of keys in the source JSON document.
+type orderedNode struct {
+	keys     []string
+	children map[string]*orderedNode
+	elements []*orderedNode
+}
+
+func marshalOrderedJSON(item interface{}, source []byte, config FormatConfig) ([]byte, error) {
+	generic, err := toGenericJSON(item)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Lenient {
+		if source, err = ParseLenientJSON(source); err != nil {
+			return nil, err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(source))
+	decoder.UseNumber()
+	node, err := readOrderedNode(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err = writeOrderedJSON(&buf, generic, node, !config.DisableEscapeHTML); err != nil {
+		return nil, err
+	}
+	if config.Indent == "" {
+		return buf.Bytes(), nil
+	}
+
+	var indented bytes.Buffer
+	if err = json.Indent(&indented, buf.Bytes(), "", config.Indent); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// readOrderedNode reads the order of keys of the next JSON value from decoder.
+func readOrderedNode(decoder *json.Decoder) (*orderedNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &orderedNode{}
+	switch token {
+	case json.Delim('{'):
+		node.children = make(map[string]*orderedNode)
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			child, err := readOrderedNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := node.children[key]; !ok {
+				node.keys = append(node.keys, key)
+			}
+			node.children[key] = child
+		}
+		_, err = decoder.Token()
+	case json.Delim('['):
+		for decoder.More() {
+			element, err := readOrderedNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.elements = append(node.elements, element)
+		}
+		_, err = decoder.Token()
+	}
+	return node, err
+}
+
+// writeOrderedJSON writes the keys of objects in the order of node, the renamed keys are matched with the original
+// ones ignoring case and separators, and the new keys follow in order.
+// writeOrderedJSON writes the keys in the order of source, the renamed keys are matched with the original ones ignoring
+// case and separators, so the keys renamed otherwise, e.g. by KeyMapping, follow as the new keys. The elements of
+// arrays are ordered by the source elements at the same indexes, unless the lengths of arrays differ.
+func writeOrderedJSON(buf *bytes.Buffer, item interface{}, node *orderedNode, escapeHTML bool) error {
+	switch v := item.(type) {
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		orderedKeys := make([]string, 0, len(keys))
+		originalKeys := make(map[string]string, len(keys))
+		used := make(map[string]bool, len(keys))
+		if node != nil {
+			for _, originalKey := range node.keys {
+				if key, ok := matchFormattedKey(originalKey, keys, used); ok {
+					used[key] = true
+					orderedKeys = append(orderedKeys, key)
+					originalKeys[key] = originalKey
+				}
+			}
+		}
+		for _, key := range keys {
+			if !used[key] {
+				orderedKeys = append(orderedKeys, key)
+			}
+		}
+
+		buf.WriteByte('{')
+		for index, key := range orderedKeys {
+			if index > 0 {
+				buf.WriteByte(',')
+			}
+			data, err := encodeJSON(key, "", escapeHTML)
+			if err != nil {
+				return err
+			}
+			buf.Write(data)
+			buf.WriteByte(':')
+
+			var child *orderedNode
+			if originalKey, ok := originalKeys[key]; ok {
+				child = node.children[originalKey]
+			}
+			if err = writeOrderedJSON(buf, v[key], child, escapeHTML); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		buf.WriteByte('[')
+		for index, element := range v {
+			if index > 0 {
+				buf.WriteByte(',')
+			}
+			// the elements are matched by indexes, which are unknown once some elements are dropped.
+			var child *orderedNode
+			if node != nil && len(node.elements) == len(v) {
+				child = node.elements[index]
+			}
+			if err := writeOrderedJSON(buf, element, child, escapeHTML); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	default:
+		data, err := encodeJSON(v, "", escapeHTML)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+}
@@ -0,0 +1,159 @@
+package normalizejson
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOutput(t *testing.T) {
+	template := []byte(`{"__keys":"camel_to_snake","count":"to_int64"}`)
+	source := []byte(`{"zeta":"a<b","userId":1,"count":"12","nested":{"y":true,"x":[{"b":1,"a":2}]},"alpha":null}`)
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"alpha":null,"count":12,"nested":{"x":[{"a":2,"b":1}],"y":true},"user_id":1,"zeta":"a\u003cb"}`, string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputPretty, DisableEscapeHTML: true, TrailingNewline: true})
+	formatted, err = provider.FormatJSONSchema([]byte(`{"zeta":"a<b","list":[1]}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "{\n  \"list\": [\n    1\n  ],\n  \"zeta\": \"a<b\"\n}\n", string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputOrdered})
+	formatted, err = provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"zeta":"a\u003cb","user_id":1,"count":12,"nested":{"y":true,"x":[{"b":1,"a":2}]},"alpha":null}`, string(formatted))
+
+	// the elements of the array with dropped elements are not matched by indexes.
+	keep := FormatDataOption("keep", func(item interface{}) (interface{}, error) {
+		return item, nil
+	})
+	dropped, err := NewFormatProvider([]byte(`{"rows":[{"__function":"keep","__null":"drop"}]}`), keep)
+	if err != nil {
+		panic(err)
+	}
+	dropped.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputOrdered})
+	formatted, err = dropped.FormatJSONSchema([]byte(`{"rows":[null,{"y":1,"x":2},{"x":3,"y":4}],"list":[{"y":1,"x":2}]}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"rows":[{"x":2,"y":1},{"x":3,"y":4}],"list":[{"y":1,"x":2}]}`, string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputOrdered, Indent: "\t"})
+	formatted, err = provider.FormatJSONSchema([]byte(`{"b":1,"a":2}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "{\n\t\"b\": 1,\n\t\"a\": 2\n}", string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputCanonical})
+	formatted, err = provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"alpha":null,"count":12,"nested":{"x":[{"a":2,"b":1}],"y":true},"user_id":1,"zeta":"a<b"}`, string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: "yaml"})
+	_, err = provider.FormatJSONSchema(source)
+	assert.NotNil(t, err)
+}
+
+func TestFormatOutputUntouched(t *testing.T) {
+	// the data is encoded with the output options even if nothing is to be normalized.
+	provider, err := NewFormatProvider(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	source := []byte(`{"b": 1.50, "a": "x"}`)
+	formatted, err := provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, string(source), string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{UseNumber: true, Output: OutputCanonical})
+	formatted, err = provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"a":"x","b":1.5}`, string(formatted))
+
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputOrdered, Lenient: true})
+	formatted, err = provider.FormatJSONSchema([]byte(`{b: 1, /* first */ a: 'x',}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, `{"b":1,"a":"x"}`, string(formatted))
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		source string
+		result string
+	}{
+		// the examples of numbers in RFC 8785.
+		{`0`, `0`},
+		{`-0`, `0`},
+		{`5e-324`, `5e-324`},
+		{`-5e-324`, `-5e-324`},
+		{`1.7976931348623157e308`, `1.7976931348623157e+308`},
+		{`9007199254740992`, `9007199254740992`},
+		{`-9007199254740992`, `-9007199254740992`},
+		{`295147905179352830000`, `295147905179352830000`},
+		{`1e21`, `1e+21`},
+		{`1e-7`, `1e-7`},
+		{`0.000001`, `0.000001`},
+		{`123.45e-2`, `1.2345`},
+		{`4.50`, `4.5`},
+		{`2e-3`, `0.002`},
+		{`333333333.33333329`, `333333333.3333333`},
+		{`1E30`, `1e+30`},
+		// the strings escape only the quotes, the backslashes and the control characters.
+		{`"€$\u000f\u000aA'B\"\\\\\"\/<>&"`, `"€$\u000f\nA'B\"\\\\\"/<>&"`},
+		// the keys are sorted by their UTF-16 code units.
+		{`{"€":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","😀":"Emoji: Grinning Face","\u0080":"Control","ö":"Latin Small Letter O With Diaeresis"}`,
+			`{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","ö":"Latin Small Letter O With Diaeresis","€":"Euro Sign","😀":"Emoji: Grinning Face","` + "\ufb33" + `":"Hebrew Letter Dalet With Dagesh"}`},
+		{`[ 1, {"b": [true, null], "a": {}} , [] ]`, `[1,{"a":{},"b":[true,null]},[]]`},
+	}
+
+	for _, test := range tests {
+		result, err := CanonicalJSON([]byte(test.source))
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, test.result, string(result), test.source)
+	}
+
+	_, err := CanonicalJSON([]byte(`1e400`))
+	assert.NotNil(t, err)
+}
+
+func TestFormatNDJSONOutput(t *testing.T) {
+	provider, err := NewFormatProvider([]byte(`{"count":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+	provider.(ConfigurableProvider).UpdateConfig(FormatConfig{Output: OutputPretty, TrailingNewline: true})
+
+	var buf bytes.Buffer
+	_, err = FormatNDJSON(context.Background(), provider, strings.NewReader("{\"count\":\"1\"}\n{\"count\":\"2\",\"a\":[1]}\n"), &buf, BatchConfig{})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "{\"count\":1}\n{\"a\":[1],\"count\":2}\n", buf.String())
+}
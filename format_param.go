@@ -0,0 +1,73 @@
+package normalizejson
+
+import (
+	"fmt"
+
+	"github.com/spf13/cast"
+)
+
+// The helpers to read the parameters of a function leaf in template, e.g.
+// {"__function":"to_time","layouts":["rfc3339"],"output":"epoch_ms"}.
+
+func paramString(params map[string]interface{}, key string, defaultValue string) (string, error) {
+	value, ok := params[key]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return defaultValue, fmt.Errorf("illegal parameter %s: %v", key, value)
+	}
+	return str, nil
+}
+
+func paramStringList(params map[string]interface{}, key string, defaultValue []string) ([]string, error) {
+	value, ok := params[key]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		strList := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return defaultValue, fmt.Errorf("illegal parameter %s: %v", key, value)
+			}
+			strList = append(strList, str)
+		}
+		return strList, nil
+	default:
+		return defaultValue, fmt.Errorf("illegal parameter %s: %v", key, value)
+	}
+}
+
+func paramInt(params map[string]interface{}, key string, defaultValue int) (int, error) {
+	value, ok := params[key]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+
+	i, err := cast.ToIntE(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("illegal parameter %s: %v", key, value)
+	}
+	return i, nil
+}
+
+func paramBool(params map[string]interface{}, key string, defaultValue bool) (bool, error) {
+	value, ok := params[key]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return defaultValue, fmt.Errorf("illegal parameter %s: %v", key, value)
+	}
+	return b, nil
+}
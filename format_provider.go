@@ -1,5 +1,17 @@
 package normalizejson
 
+import "context"
+
+var (
+	_ ConfigurableProvider = (*formatSchemaImpl)(nil)
+	_ ConfigurableProvider = (*formatDataImpl)(nil)
+	_ ConfigurableProvider = (*formatKeyImpl)(nil)
+
+	_ ContextFormatProvider = (*formatSchemaImpl)(nil)
+	_ ContextFormatProvider = (*formatDataImpl)(nil)
+	_ ContextFormatProvider = (*formatKeyImpl)(nil)
+)
+
 func NewFormatSchemaProvider(rawTemplate []byte, options ...FormatOption) (FormatProvider, error) {
 	return newFormatSchemaImpl(rawTemplate, options...)
 }
@@ -16,8 +28,16 @@ func (fsi *formatSchemaImpl) UpdateTemplate(rawTemplate []byte) error {
 	return fsi.updateTemplate(rawTemplate)
 }
 
+func (fsi *formatSchemaImpl) UpdateConfig(config FormatConfig) {
+	fsi.config = config
+}
+
 func (fsi *formatSchemaImpl) FormatJSONSchema(data []byte) ([]byte, error) {
-	return fsi.formatJSONSchema(data)
+	return fsi.formatJSONSchema(context.Background(), data)
+}
+
+func (fsi *formatSchemaImpl) FormatJSONSchemaContext(ctx context.Context, data []byte) ([]byte, error) {
+	return fsi.formatJSONSchema(ctx, data)
 }
 
 func (fsi *formatSchemaImpl) Reset() {
@@ -40,8 +60,16 @@ func (fdi *formatDataImpl) UpdateTemplate(rawTemplate []byte) error {
 	return fdi.updateTemplate(rawTemplate)
 }
 
+func (fdi *formatDataImpl) UpdateConfig(config FormatConfig) {
+	fdi.config = config
+}
+
 func (fdi *formatDataImpl) FormatJSONSchema(data []byte) ([]byte, error) {
-	return fdi.formatJSONSchema(data)
+	return fdi.formatJSONSchema(context.Background(), data)
+}
+
+func (fdi *formatDataImpl) FormatJSONSchemaContext(ctx context.Context, data []byte) ([]byte, error) {
+	return fdi.formatJSONSchema(ctx, data)
 }
 
 func (fdi *formatDataImpl) Reset() {
@@ -61,8 +89,16 @@ func (fki *formatKeyImpl) UpdateTemplate(rawTemplate []byte) error {
 	return nil
 }
 
+func (fki *formatKeyImpl) UpdateConfig(config FormatConfig) {
+	fki.config = config
+}
+
 func (fki *formatKeyImpl) FormatJSONSchema(data []byte) ([]byte, error) {
-	return fki.formatJSONSchema(data)
+	return fki.formatJSONSchema(context.Background(), data)
+}
+
+func (fki *formatKeyImpl) FormatJSONSchemaContext(ctx context.Context, data []byte) ([]byte, error) {
+	return fki.formatJSONSchema(ctx, data)
 }
 
 func (fki *formatKeyImpl) Reset() {
@@ -0,0 +1,280 @@
+package normalizejson
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	FormatRegexReplace = "regex_replace"
+	FormatRegexExtract = "regex_extract"
+	FormatSplit        = "split"
+	FormatJoin         = "join"
+)
+
+const (
+	extractUnmatchedNull        = "null"
+	extractUnmatchedError       = "error"
+	extractUnmatchedPassthrough = "passthrough"
+)
+
+// BuildFormatDataRegexReplace creates the function to replace the matches of regex in strings, which is a built-in
+// function of template and requires no option. The parameters in function leaf of template:
+//
+//   - "pattern": the regex, which is required.
+//   - "replacement": the replacement with the expansion of "$1" and "${name}", "" by default.
+//   - "literal": take the replacement literally without expansion.
+//
+// E.g. {"__function":"regex_replace","pattern":"\\s+","replacement":" "}.
+func BuildFormatDataRegexReplace(params map[string]interface{}) (FormatFunc, error) {
+	re, err := paramRegexp(params, "pattern")
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := paramString(params, "replacement", "")
+	if err != nil {
+		return nil, err
+	}
+
+	literal, err := paramBool(params, "literal", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatString(item, func(str string) string {
+			if literal {
+				return re.ReplaceAllLiteralString(str, replacement)
+			}
+			return re.ReplaceAllString(str, replacement)
+		})
+	}, nil
+}
+
+// BuildFormatDataRegexExtract creates the function to extract the submatches of regex from strings, which is a built-in
+// function of template and requires no option. The parameters in function leaf of template:
+//
+//   - "pattern": the regex, which is required. With named groups, the submatches are extracted as a JSON object
+//     by the names, otherwise the first group (or the whole match without groups) is extracted as a string.
+//   - "group": the name or index of the group to extract as a string.
+//   - "all": extract all the matches as a JSON array.
+//   - "unmatched": the behavior for the unmatched strings, one of "null" (default), "error" and "passthrough".
+//
+// E.g. {"__function":"regex_extract","pattern":"^ID-0*(\\d+)$"} extracts "423" from "ID-00423".
+func BuildFormatDataRegexExtract(params map[string]interface{}) (FormatFunc, error) {
+	re, err := paramRegexp(params, "pattern")
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := paramBool(params, "all", false)
+	if err != nil {
+		return nil, err
+	}
+
+	unmatched, err := paramString(params, "unmatched", extractUnmatchedNull)
+	if err != nil {
+		return nil, err
+	}
+	if unmatched != extractUnmatchedNull && unmatched != extractUnmatchedError && unmatched != extractUnmatchedPassthrough {
+		return nil, fmt.Errorf("unknown unmatched behavior %s", unmatched)
+	}
+
+	extract, err := newRegexExtractor(re, params["group"])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		str, ok := item.(string)
+		if !ok {
+			return item, nil
+		}
+
+		var extracted interface{}
+		if all {
+			matchesList := re.FindAllStringSubmatch(str, -1)
+			if len(matchesList) > 0 {
+				extractedList := make([]interface{}, 0, len(matchesList))
+				for _, matches := range matchesList {
+					extractedList = append(extractedList, extract(matches))
+				}
+				extracted = extractedList
+			}
+		} else if matches := re.FindStringSubmatch(str); matches != nil {
+			extracted = extract(matches)
+		}
+
+		if extracted != nil {
+			return extracted, nil
+		}
+		switch unmatched {
+		case extractUnmatchedError:
+			return item, fmt.Errorf("unmatched value %s", str)
+		case extractUnmatchedPassthrough:
+			return item, nil
+		default:
+			return nil, nil
+		}
+	}, nil
+}
+
+// newRegexExtractor creates the function to take the extracted value from the submatches of re.
+func newRegexExtractor(re *regexp.Regexp, group interface{}) (func(matches []string) interface{}, error) {
+	switch v := group.(type) {
+	case nil:
+	case string:
+		index := re.SubexpIndex(v)
+		if index < 0 {
+			return nil, fmt.Errorf("unknown group %s", v)
+		}
+		group = index
+	default:
+		index, err := cast.ToIntE(v)
+		if err != nil || index < 0 || index > re.NumSubexp() {
+			return nil, fmt.Errorf("illegal parameter group: %v", v)
+		}
+		group = index
+	}
+
+	if index, ok := group.(int); ok {
+		return func(matches []string) interface{} {
+			return matches[index]
+		}, nil
+	}
+
+	var names []string
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		index := 0
+		if re.NumSubexp() > 0 {
+			index = 1
+		}
+		return func(matches []string) interface{} {
+			return matches[index]
+		}, nil
+	}
+
+	return func(matches []string) interface{} {
+		extracted := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			extracted[name] = matches[re.SubexpIndex(name)]
+		}
+		return extracted
+	}, nil
+}
+
+// BuildFormatDataSplit creates the function to split strings into JSON arrays, which is a built-in function of
+// template and requires no option. The parameters in function leaf of template:
+//
+//   - "separator": the separator, "," by default.
+//   - "pattern": the regex of separator instead of "separator".
+//   - "trim": trim the white spaces around the elements.
+//   - "omit_empty": remove the empty elements.
+//
+// E.g. {"__function":"split","separator":",","trim":true,"omit_empty":true}.
+func BuildFormatDataSplit(params map[string]interface{}) (FormatFunc, error) {
+	separator, err := paramString(params, "separator", ",")
+	if err != nil {
+		return nil, err
+	}
+
+	var re *regexp.Regexp
+	if _, ok := params["pattern"]; ok {
+		if re, err = paramRegexp(params, "pattern"); err != nil {
+			return nil, err
+		}
+	}
+
+	trim, err := paramBool(params, "trim", false)
+	if err != nil {
+		return nil, err
+	}
+
+	omitEmpty, err := paramBool(params, "omit_empty", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		str, ok := item.(string)
+		if !ok {
+			return item, nil
+		}
+
+		var parts []string
+		if re != nil {
+			parts = re.Split(str, -1)
+		} else {
+			parts = strings.Split(str, separator)
+		}
+
+		splitList := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			if trim {
+				part = strings.TrimSpace(part)
+			}
+			if omitEmpty && part == "" {
+				continue
+			}
+			splitList = append(splitList, part)
+		}
+		return splitList, nil
+	}, nil
+}
+
+// BuildFormatDataJoin creates the function to join the scalar values of JSON arrays into strings, which is a built-in
+// function of template and requires no option. The parameter "separator" is "," by default, and the null elements
+// are skipped.
+//
+// E.g. {"__function":"join","separator":"|"}.
+func BuildFormatDataJoin(params map[string]interface{}) (FormatFunc, error) {
+	separator, err := paramString(params, "separator", ",")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		itemList, ok := item.([]interface{})
+		if !ok {
+			return item, nil
+		}
+
+		strList := make([]string, 0, len(itemList))
+		for _, element := range itemList {
+			if element == nil {
+				continue
+			}
+			str, ok := enumString(element)
+			if !ok {
+				return item, fmt.Errorf("unable to join %v", element)
+			}
+			strList = append(strList, str)
+		}
+		return strings.Join(strList, separator), nil
+	}, nil
+}
+
+func paramRegexp(params map[string]interface{}, key string) (*regexp.Regexp, error) {
+	pattern, err := paramString(params, key, "")
+	if err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		return nil, fmt.Errorf("no parameter %s", key)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("illegal parameter %s: %s", key, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,47 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRegex(t *testing.T) {
+	dir := "format_regex"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := JSONSchemaFormat(source, template)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestFormatRegexError(t *testing.T) {
+	_, err := JSONSchemaFormat([]byte(`{"id":"X-1"}`), []byte(`{"id":{"__function":"regex_extract","pattern":"^ID-(\\d+)$","unmatched":"error"}}`))
+	assert.NotNil(t, err)
+
+	for _, template := range []string{
+		`{"id":{"__function":"regex_extract"}}`,
+		`{"id":{"__function":"regex_extract","pattern":"("}}`,
+		`{"id":{"__function":"regex_extract","pattern":"(\\d+)","group":"digits"}}`,
+		`{"id":{"__function":"regex_replace","pattern":"\\d","literal":"yes"}}`,
+	} {
+		_, err := JSONSchemaFormat([]byte(`{"id":"ID-1"}`), []byte(template))
+		assert.NotNil(t, err, template)
+	}
+}
@@ -1,24 +1,125 @@
 package normalizejson
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
 func JSONSchemaFormat(data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error) {
+	return JSONSchemaFormatContext(context.Background(), data, rawTemplate, options...)
+}
+
+func JSONSchemaFormatContext(ctx context.Context, data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error) {
 	fsi, err := newFormatSchemaImpl(rawTemplate, options...)
 	if err != nil {
 		return nil, fmt.Errorf("format JSON data failed: %s", err)
 	}
-	return fsi.formatJSONSchema(data)
+	return fsi.formatJSONSchema(ctx, data)
+}
+
+const (
+	formatSchemaKeysDirective       = "__keys"
+	formatSchemaAdditionalDirective = "__additional"
+	// the prefix of the templates at root referred only by "__template.", such as the definitions of JSON Schema,
+	// which never apply to the JSON keys.
+	formatSchemaDefPrefix = "__def_"
+)
+
+// formatScope holds the settings inherited by a JSON value from the templates of its ancestors,
+// and where the JSON value is.
+type formatScope struct {
+	keyFuncs       []FormatFunc // nil means taking the key-options of provider.
+	redactPatterns []*regexp.Regexp
+	ctx            context.Context
+	root           interface{}
+	parent         interface{}
+	path           *formatPath
+}
+
+// withRedact returns the scope taking the "__redact" directive of templateMap, which applies to the JSON object it
+// describes and the descendants.
+func (scope formatScope) withRedact(templateMap map[string]interface{}) formatScope {
+	if node, ok := templateMap[formatTemplateRedactDirective].(*formatRedactNode); ok {
+		redactPatterns := make([]*regexp.Regexp, 0, len(scope.redactPatterns)+len(node.patterns))
+		redactPatterns = append(redactPatterns, scope.redactPatterns...)
+		scope.redactPatterns = append(redactPatterns, node.patterns...)
+	}
+	return scope
+}
+
+// redactItem redacts the values whose keys match the patterns of scope in item, which is not formatted by template.
+func (scope formatScope) redactItem(item interface{}) interface{} {
+	switch v := item.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if scope.needRedact(key) {
+				v[key] = RedactedValue
+			} else {
+				v[key] = scope.redactItem(child)
+			}
+		}
+	case []interface{}:
+		for index, element := range v {
+			v[index] = scope.redactItem(element)
+		}
+	}
+	return item
+}
+
+func newFormatScope(ctx context.Context, root interface{}) formatScope {
+	return formatScope{ctx: ctx, root: root}
+}
+
+// child returns the scope of the value of key in the JSON object parent.
+func (scope formatScope) child(key string, parent map[string]interface{}) formatScope {
+	scope.parent = parent
+	scope.path = scope.path.child(key)
+	return scope
+}
+
+// element returns the scope of the element at index in the JSON array parent.
+func (scope formatScope) element(index int, parent []interface{}) formatScope {
+	scope.parent = parent
+	scope.path = scope.path.element(index)
+	return scope
+}
+
+// formatContext creates the context for the data function referred by template.
+func (scope formatScope) formatContext(template interface{}, config FormatConfig) *FormatContext {
+	if node, ok := template.(*formatFuncNode); ok {
+		template = node.raw
+	}
+	return &FormatContext{
+		Context:  scope.ctx,
+		Parent:   scope.parent,
+		Root:     scope.root,
+		Template: template,
+		Config:   config,
+		path:     scope.path,
+	}
+}
+
+func (scope formatScope) needRedact(keys ...string) bool {
+	for _, re := range scope.redactPatterns {
+		for _, key := range keys {
+			if re.MatchString(key) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 type formatSchemaImpl struct {
-	retainKey   bool
-	formatKFunc map[string]FormatFunc
-	formatVFunc map[string]FormatFunc
-	templateMap map[string]interface{}
+	retainKey      bool
+	formatKFunc    map[string]FormatFunc
+	formatVFunc    map[string]FormatContextFunc
+	formatVBuilder map[string]FormatFuncBuilder
+	templateMap    map[string]interface{}
+	templateErr    error
+	config         FormatConfig
 }
 
 func newFormatSchemaImpl(rawTemplate []byte, options ...FormatOption) (*formatSchemaImpl, error) {
@@ -37,16 +138,23 @@ func newFormatSchemaImpl(rawTemplate []byte, options ...FormatOption) (*formatSc
 
 func (fsi *formatSchemaImpl) reset() {
 	fsi.formatKFunc = make(map[string]FormatFunc)
-	fsi.formatVFunc = make(map[string]FormatFunc)
+	fsi.formatVFunc = make(map[string]FormatContextFunc)
+	fsi.formatVBuilder = make(map[string]FormatFuncBuilder)
 	fsi.templateMap = make(map[string]interface{})
+	fsi.templateErr = nil
+	fsi.config = FormatConfig{}
 }
 
 func (fsi *formatSchemaImpl) updateTemplate(rawTemplate []byte) error {
-	templateMap := make(map[string]interface{})
-	if err := json.Unmarshal(rawTemplate, &templateMap); err != nil {
+	templateMap, err := unmarshalTemplate(rawTemplate, fsi.config)
+	if err != nil {
+		return err
+	}
+	if _, err := compileTemplate(templateMap, fsi.formatVFunc, fsi.formatVBuilder); err != nil {
 		return err
 	}
 	fsi.templateMap = templateMap
+	fsi.templateErr = nil
 	return nil
 }
 
@@ -56,53 +164,102 @@ func (fsi *formatSchemaImpl) addOptions(options ...FormatOption) {
 	}
 
 	if fsi.formatVFunc == nil {
-		fsi.formatVFunc = make(map[string]FormatFunc)
+		fsi.formatVFunc = make(map[string]FormatContextFunc)
+	}
+
+	if fsi.formatVBuilder == nil {
+		fsi.formatVBuilder = make(map[string]FormatFuncBuilder)
 	}
 
 	for _, option := range options {
-		if option.FunctionType == FormatFuncFormatKey {
+		if option.FunctionType == FormatFuncFormatConfig {
+			fsi.config = option.Config
+		} else if option.FunctionType == FormatFuncFormatKey {
 			fsi.formatKFunc[option.FunctionName] = option.FormatFunction
 			fsi.retainKey = option.RetainKey
 		} else {
 			// format_function_type_format_key
-			fsi.formatVFunc[option.FunctionName] = option.FormatFunction
+			if f := takeFormatContextFunc(option); f != nil {
+				fsi.formatVFunc[option.FunctionName] = f
+			}
+			if option.FormatBuilder != nil {
+				fsi.formatVBuilder[option.FunctionName] = option.FormatBuilder
+			}
 		}
 	}
+
+	// rebuild the function leaves in template with the new options.
+	_, fsi.templateErr = compileTemplate(fsi.templateMap, fsi.formatVFunc, fsi.formatVBuilder)
 }
 
-func (fsi *formatSchemaImpl) formatJSONSchema(data []byte) ([]byte, error) {
-	if len(fsi.formatKFunc) == 0 && len(fsi.formatVFunc) == 0 {
+func (fsi *formatSchemaImpl) formatJSONSchema(ctx context.Context, data []byte) ([]byte, error) {
+	if fsi.templateErr != nil {
+		return nil, fmt.Errorf("compile template failed: %s", fsi.templateErr)
+	}
+
+	if err := fsi.config.check(); err != nil {
+		return nil, err
+	}
+
+	if len(fsi.formatKFunc) == 0 && len(fsi.formatVFunc) == 0 && len(fsi.formatVBuilder) == 0 && len(fsi.templateMap) == 0 && fsi.config.keepsData() {
 		return data, nil
 	}
 
-	var item interface{}
-	if err := json.Unmarshal(data, &item); err != nil {
-		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	item, err := unmarshalJSON(data, fsi.config)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %w", err)
 	}
 
-	formattedItem, err := fsi.formatItem(item, nil)
+	// the directives in root template apply to the whole JSON document.
+	scope, err := fsi.takeScope(newFormatScope(ctx, item), fsi.templateMap)
 	if err != nil {
 		return nil, fmt.Errorf("format JSON data failed: %s", err)
 	}
-	return json.Marshal(formattedItem)
+
+	formattedItem, err := fsi.formatItem(scope, item, nil)
+	if err != nil {
+		return nil, fmt.Errorf("format JSON data failed: %w", err)
+	}
+	if _, ok := formattedItem.(formatDropped); ok {
+		formattedItem = nil
+	}
+	return marshalOutput(formattedItem, data, fsi.config)
 }
 
-func (fsi *formatSchemaImpl) formatItem(item interface{}, template interface{}) (interface{}, error) {
+func (fsi *formatSchemaImpl) formatItem(scope formatScope, item interface{}, template interface{}) (interface{}, error) {
+	// stop formatting once the context is cancelled.
+	if err := scope.ctx.Err(); err != nil {
+		return item, err
+	}
+
 	template = fsi.takeTemplate(template)
 
+	// the function leaf in template takes any kind of JSON value.
+	if node, ok := template.(*formatFuncNode); ok {
+		formattedItem, err := node.format(scope.formatContext(node, fsi.config), item, fsi.config)
+		return formattedItem, wrapFormatError(scope.path, err)
+	}
+	if node, ok := template.(*formatEmbeddedNode); ok {
+		return fsi.formatEmbeddedItem(scope, item, node)
+	}
+
 	switch v := item.(type) {
 	case []interface{}:
 		templateList, ok := template.([]interface{})
 		if ok {
-			return fsi.formatItemList(v, templateList)
+			return fsi.formatItemList(scope, v, templateList)
 		}
-		return fsi.formatItemList(v, nil)
+		return fsi.formatItemList(scope, v, nil)
 	case map[string]interface{}:
 		templateMap, ok := template.(map[string]interface{})
 		if ok {
-			return fsi.formatItemMap(v, templateMap)
+			return fsi.formatItemMap(scope, v, templateMap)
 		}
-		return fsi.formatItemMap(v, nil)
+		return fsi.formatItemMap(scope, v, nil)
 	default:
 		expr, ok := template.(string)
 		if !ok {
@@ -111,75 +268,268 @@ func (fsi *formatSchemaImpl) formatItem(item interface{}, template interface{})
 
 		f, ok := fsi.formatVFunc[expr]
 		if ok {
-			return f(item)
+			formattedItem, err := formatNullable(f, scope.formatContext(expr, fsi.config), item, fsi.config)
+			return formattedItem, wrapFormatError(scope.path, err)
 		}
 		return item, nil
 	}
 }
 
-func (fsi *formatSchemaImpl) formatItemList(itemList []interface{}, templateList []interface{}) ([]interface{}, error) {
+// formatEmbeddedItem formats the JSON value serialized as a string with the nested template, or serializes the
+// formatted value to a string.
+func (fsi *formatSchemaImpl) formatEmbeddedItem(scope formatScope, item interface{}, node *formatEmbeddedNode) (interface{}, error) {
+	if node.encode {
+		formattedItem, err := fsi.formatItem(scope, item, node.template)
+		if err != nil {
+			return item, err
+		}
+		formattedItem, err = node.encodeItem(formattedItem)
+		return formattedItem, wrapFormatError(scope.path, err)
+	}
+
+	decodedItem, err := node.decodeItem(item, fsi.config)
+	if err != nil {
+		return item, wrapFormatError(scope.path, err)
+	}
+	return fsi.formatItem(scope, decodedItem, node.template)
+}
+
+func (fsi *formatSchemaImpl) formatItemList(scope formatScope, itemList []interface{}, templateList []interface{}) ([]interface{}, error) {
 	var template interface{}
 
 	if len(templateList) > 0 {
 		template = templateList[0]
 	}
 
+	// the formatted elements are put into a new list, so that the original list retained with its key is unchanged.
+	formattedList := make([]interface{}, 0, len(itemList))
 	for index, item := range itemList {
-		formattedItem, err := fsi.formatItem(item, template)
+		formattedItem, err := fsi.formatItem(scope.element(index, itemList), item, template)
 		if err != nil {
 			return itemList, err
 		}
-		itemList[index] = formattedItem
+		if _, ok := formattedItem.(formatDropped); ok {
+			continue
+		}
+		formattedList = append(formattedList, formattedItem)
 	}
-	return itemList, nil
+	return formattedList, nil
 }
 
-func (fsi *formatSchemaImpl) formatItemMap(itemMap map[string]interface{}, templateMap map[string]interface{}) (map[string]interface{}, error) {
+func (fsi *formatSchemaImpl) formatItemMap(scope formatScope, itemMap map[string]interface{}, templateMap map[string]interface{}) (map[string]interface{}, error) {
+	// the directives in template apply to this JSON object and its descendants.
+	if templateMap != nil {
+		var err error
+		if scope, err = fsi.takeScope(scope, templateMap); err != nil {
+			return itemMap, err
+		}
+	}
+
+	// the formatted entries are put into a new map, so that the renamed keys are never visited again.
+	formattedMap := make(map[string]interface{}, len(itemMap))
 	for key, item := range itemMap {
 		// format JSON key at first.
-		formattedKey, err := fsi.formatKey(key)
+		formattedKey, err := fsi.formatKey(scope, key)
 		if err != nil {
-			return itemMap, err
+			return itemMap, wrapFormatError(scope.path.child(key), err)
 		}
 
 		// take the formatted key to find the template.
 		var template interface{}
-		if templateMap != nil {
-			template = templateMap[formattedKey]
+		if isFormatDirective(formattedKey) {
+			template = nil
+		} else if templateMap != nil {
+			template = templateOfKey(templateMap, formattedKey)
 		} else {
 			template = fsi.templateMap[formattedKey]
 		}
 
-		// format JSON value item with the selected template.
-		formattedItem, err := fsi.formatItem(item, template)
-		if err != nil {
+		// format JSON value item with the selected template, or redact it.
+		var formattedItem interface{}
+		if scope.needRedact(key, formattedKey) {
+			formattedItem = RedactedValue
+		} else if formattedItem, err = fsi.formatItem(scope.child(formattedKey, itemMap), item, template); err != nil {
 			return itemMap, err
 		}
 
-		// update the item_map with the formatted JSON key and value.
-		if !fsi.retainKey {
-			delete(itemMap, key)
+		// take the formatted JSON key and value, the original key is retained with its value unless it is taken by
+		// a formatted key.
+		if _, ok := formattedMap[key]; fsi.retainKey && !ok {
+			if scope.needRedact(key, formattedKey) {
+				formattedMap[key] = RedactedValue
+			} else if formattedMap[key], err = fsi.redactRetainedItem(scope, item, template); err != nil {
+				return itemMap, err
+			}
+		}
+		if _, ok := formattedItem.(formatDropped); ok {
+			delete(formattedMap, formattedKey)
+			continue
 		}
-		itemMap[formattedKey] = formattedItem
+		formattedMap[formattedKey] = formattedItem
 	}
 
-	return itemMap, nil
+	return formattedMap, nil
+}
+
+// redactRetainedItem redacts the original value retained with its key as the formatted value, the keys of its
+// descendants are matched by both the original and the formatted names.
+func (fsi *formatSchemaImpl) redactRetainedItem(scope formatScope, item interface{}, template interface{}) (interface{}, error) {
+	template = fsi.takeTemplate(template)
+
+	switch v := item.(type) {
+	case []interface{}:
+		var elementTemplate interface{}
+		if templateList, ok := template.([]interface{}); ok && len(templateList) > 0 {
+			elementTemplate = templateList[0]
+		}
+
+		redactedList := make([]interface{}, 0, len(v))
+		for _, element := range v {
+			redactedElement, err := fsi.redactRetainedItem(scope, element, elementTemplate)
+			if err != nil {
+				return item, err
+			}
+			redactedList = append(redactedList, redactedElement)
+		}
+		return redactedList, nil
+	case map[string]interface{}:
+		templateMap, _ := template.(map[string]interface{})
+		if templateMap != nil {
+			var err error
+			if scope, err = fsi.takeScope(scope, templateMap); err != nil {
+				return item, err
+			}
+		}
+
+		redactedMap := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			formattedKey, err := fsi.formatKey(scope, key)
+			if err != nil {
+				return item, err
+			}
+			if scope.needRedact(key, formattedKey) {
+				redactedMap[key] = RedactedValue
+				continue
+			}
+
+			var childTemplate interface{}
+			if isFormatDirective(formattedKey) {
+				childTemplate = nil
+			} else if templateMap != nil {
+				childTemplate = templateOfKey(templateMap, formattedKey)
+			} else {
+				childTemplate = fsi.templateMap[formattedKey]
+			}
+			if redactedMap[key], err = fsi.redactRetainedItem(scope, child, childTemplate); err != nil {
+				return item, err
+			}
+		}
+		return redactedMap, nil
+	default:
+		return item, nil
+	}
 }
 
-func (fsi *formatSchemaImpl) formatKey(key string) (string, error) {
+func (fsi *formatSchemaImpl) formatKey(scope formatScope, key string) (string, error) {
+	if scope.keyFuncs != nil {
+		for _, f := range scope.keyFuncs {
+			formattedKey, err := formatKeyByFunc(key, f)
+			if err != nil {
+				return key, err
+			}
+			key = formattedKey
+		}
+		return key, nil
+	}
+
 	for _, f := range fsi.formatKFunc {
-		formatted, err := f(key)
+		formattedKey, err := formatKeyByFunc(key, f)
 		if err != nil {
 			return key, err
 		}
+		key = formattedKey
+	}
+	return key, nil
+}
+
+func formatKeyByFunc(key string, f FormatFunc) (string, error) {
+	formatted, err := f(key)
+	if err != nil {
+		return key, err
+	}
+
+	formattedKey, ok := formatted.(string)
+	if !ok {
+		return key, fmt.Errorf("illegal converted type")
+	}
+	return formattedKey, nil
+}
+
+// takeScope reads the directives of templateMap and returns the scope for the JSON object it describes.
+func (fsi *formatSchemaImpl) takeScope(scope formatScope, templateMap map[string]interface{}) (formatScope, error) {
+	if directive, ok := templateMap[formatSchemaKeysDirective]; ok {
+		keyFuncs, err := fsi.takeKeyFuncs(directive)
+		if err != nil {
+			return scope, err
+		}
+		scope.keyFuncs = keyFuncs
+	}
+
+	return scope.withRedact(templateMap), nil
+}
+
+// takeKeyFuncs resolves the key functions named by a "__keys" directive, which is a function name or a list of them.
+// The names are looked up in the key-options of provider at first, then in DefaultFormatKeyOptions.
+func (fsi *formatSchemaImpl) takeKeyFuncs(directive interface{}) ([]FormatFunc, error) {
+	var names []string
+	switch v := directive.(type) {
+	case string:
+		if v != "" {
+			names = append(names, v)
+		}
+	case []interface{}:
+		for _, name := range v {
+			str, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("illegal %s directive: %v", formatSchemaKeysDirective, directive)
+			}
+			names = append(names, str)
+		}
+	default:
+		return nil, fmt.Errorf("illegal %s directive: %v", formatSchemaKeysDirective, directive)
+	}
 
-		formattedKey, ok := formatted.(string)
+	// an empty list means keeping the JSON keys as they are.
+	keyFuncs := make([]FormatFunc, 0, len(names))
+	for _, name := range names {
+		f, ok := fsi.formatKFunc[name]
 		if !ok {
-			return key, fmt.Errorf("illegal converted type")
+			f, ok = defaultFormatKeyFunc(name)
 		}
-		key = formattedKey
+		if !ok {
+			return nil, fmt.Errorf("unknown key function %s", name)
+		}
+		keyFuncs = append(keyFuncs, f)
 	}
-	return key, nil
+	return keyFuncs, nil
+}
+
+func isFormatDirective(key string) bool {
+	switch key {
+	case formatSchemaKeysDirective, formatSchemaAdditionalDirective, formatTemplateRedactDirective:
+		return true
+	default:
+		return strings.HasPrefix(key, formatSchemaDefPrefix)
+	}
+}
+
+// templateOfKey finds the template of key in the template of a JSON object, the keys not in it take the template of
+// the directive "__additional".
+func templateOfKey(templateMap map[string]interface{}, key string) interface{} {
+	if template, ok := templateMap[key]; ok {
+		return template
+	}
+	return templateMap[formatSchemaAdditionalDirective]
 }
 
 func (fsi *formatSchemaImpl) takeTemplate(template interface{}) interface{} {
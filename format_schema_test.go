@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/spf13/cast"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -108,3 +109,108 @@ func TestFormatSchemaProviderAndUpdateTemplateAndReset(t *testing.T) {
 
 	assert.Equal(t, formatJSON(source), formatJSON(formattedFailed))
 }
+
+func TestFormatSchemaKeysDirective(t *testing.T) {
+	dir := "format_schema"
+	template, err := readTestData(dir, "config_keys.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	// no key-option is added, the template decides how to format JSON keys.
+	formatted, err := JSONSchemaFormat(source, template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	templateOverride, err := readTestData(dir, "config_keys_override.json")
+	if err != nil {
+		panic(err)
+	}
+
+	resultOverride, err := readTestData(dir, "result_keys_override.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formattedOverride, err := JSONSchemaFormat(source, templateOverride, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(resultOverride), formatJSON(formattedOverride))
+
+	_, err = JSONSchemaFormat(source, []byte(`{"__keys":"unknown"}`))
+	assert.NotNil(t, err)
+}
+
+func TestFormatSchemaRenamedKeysOnce(t *testing.T) {
+	// the function is not idempotent, the renamed keys should be formatted once.
+	increase := FormatDataOption("increase", func(item interface{}) (interface{}, error) {
+		i, err := cast.ToInt64E(item)
+		return i + 1, err
+	})
+
+	template := []byte(`{"__keys":"camel_to_snake","value_a":"increase","value_b":"increase","value_c":"increase","value_d":"increase","value_e":"increase","value_f":"increase","value_g":"increase","value_h":"increase","value_i":"increase","value_j":"increase","value_k":"increase","value_l":"increase","value_m":"increase","value_n":"increase","value_o":"increase","value_p":"increase"}`)
+	source := []byte(`{"valueA":1,"valueB":1,"valueC":1,"valueD":1,"valueE":1,"valueF":1,"valueG":1,"valueH":1,"valueI":1,"valueJ":1,"valueK":1,"valueL":1,"valueM":1,"valueN":1,"valueO":1,"valueP":1}`)
+	for index := 0; index < 200; index++ {
+		formatted, err := JSONSchemaFormat(source, template, increase)
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, `{"value_a":2,"value_b":2,"value_c":2,"value_d":2,"value_e":2,"value_f":2,"value_g":2,"value_h":2,"value_i":2,"value_j":2,"value_k":2,"value_l":2,"value_m":2,"value_n":2,"value_o":2,"value_p":2}`, string(formatted))
+	}
+}
+
+func TestFormatSchemaRetainKeyList(t *testing.T) {
+	var keyOption FormatOption
+	for _, option := range DefaultFormatKeyOptions {
+		if option.FunctionName == FormatCamelToSnake {
+			keyOption = option
+		}
+	}
+	keyOption.RetainKey = true
+
+	// the lists retained with the original keys are not changed by formatting.
+	template := []byte(`{"user_ids":["to_int64"],"user_tags":[{"__function":"to_string","__null":"drop"}]}`)
+	source := []byte(`{"userIds":["1","2"],"userTags":[null,1,null,2]}`)
+	formatted, err := JSONSchemaFormat(source, template, append([]FormatOption{keyOption}, DefaultFormatDataOptions...)...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"userIds":["1","2"],"user_ids":[1,2],"userTags":[null,1,null,2],"user_tags":["1","2"]}`)), formatJSON(formatted))
+}
+
+func TestFormatSchemaRetainKeyRedact(t *testing.T) {
+	var keyOption FormatOption
+	for _, option := range DefaultFormatKeyOptions {
+		if option.FunctionName == FormatCamelToSnake {
+			keyOption = option
+		}
+	}
+	keyOption.RetainKey = true
+
+	// the values retained with the original keys are redacted as the formatted ones.
+	template := []byte(`{"__redact":["^card_number$"],"nested":{"__redact":["^api_token$"]},"items":[{"__redact":["^api_token$"]}]}`)
+	source := []byte(`{"cardNumber":"4111111111111111","nested":{"apiToken":"secret","name":"a"},"listItems":{"apiToken":"secret"},"items":[{"apiToken":"secret"}]}`)
+	formatted, err := JSONSchemaFormat(source, template, keyOption)
+	if err != nil {
+		panic(err)
+	}
+	expected := []byte(`{"cardNumber":"[REDACTED]","card_number":"[REDACTED]","nested":{"apiToken":"[REDACTED]","api_token":"[REDACTED]","name":"a"},` +
+		`"listItems":{"apiToken":"secret"},"list_items":{"apiToken":"secret","api_token":"secret"},` +
+		`"items":[{"apiToken":"[REDACTED]","api_token":"[REDACTED]"}]}`)
+	assert.Equal(t, formatJSON(expected), formatJSON(formatted))
+}
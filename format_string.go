@@ -0,0 +1,167 @@
+package normalizejson
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	FormatTrim               = "trim"
+	FormatToLower            = "to_lower"
+	FormatToUpper            = "to_upper"
+	FormatToTitle            = "to_title"
+	FormatToNFC              = "to_nfc"
+	FormatToNFD              = "to_nfd"
+	FormatToNFKC             = "to_nfkc"
+	FormatToNFKD             = "to_nfkd"
+	FormatCollapseWhitespace = "collapse_whitespace"
+	FormatStripControl       = "strip_control"
+	FormatStripZeroWidth     = "strip_zero_width"
+	FormatTruncate           = "truncate"
+)
+
+// StringFormatDataOptions are the data-options to clean strings, the values which are not string are retained.
+//
+// The parameters in function leaf of template:
+//
+//   - "trim": "cutset" is the characters to remove from both ends, the white spaces are removed by default.
+//   - "truncate": "max_length" is the max count of runes (required), and "ellipsis" is appended to the truncated string
+//     within the max length.
+//
+// E.g. {"__function":"truncate","max_length":64,"ellipsis":"..."}.
+var StringFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatTrim, BuildFormatDataTrim),
+	FormatDataOption(FormatToLower, FormatDataToLower),
+	FormatDataOption(FormatToUpper, FormatDataToUpper),
+	FormatDataOption(FormatToTitle, FormatDataToTitle),
+	FormatDataOption(FormatToNFC, FormatDataToNFC),
+	FormatDataOption(FormatToNFD, FormatDataToNFD),
+	FormatDataOption(FormatToNFKC, FormatDataToNFKC),
+	FormatDataOption(FormatToNFKD, FormatDataToNFKD),
+	FormatDataOption(FormatCollapseWhitespace, FormatDataCollapseWhitespace),
+	FormatDataOption(FormatStripControl, FormatDataStripControl),
+	FormatDataOption(FormatStripZeroWidth, FormatDataStripZeroWidth),
+	FormatDataBuilderOption(FormatTruncate, BuildFormatDataTruncate),
+}
+
+func BuildFormatDataTrim(params map[string]interface{}) (FormatFunc, error) {
+	cutset, err := paramString(params, "cutset", "")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatString(item, func(str string) string {
+			if cutset == "" {
+				return strings.TrimSpace(str)
+			}
+			return strings.Trim(str, cutset)
+		})
+	}, nil
+}
+
+func FormatDataToLower(item interface{}) (interface{}, error) {
+	return formatString(item, strings.ToLower)
+}
+
+func FormatDataToUpper(item interface{}) (interface{}, error) {
+	return formatString(item, strings.ToUpper)
+}
+
+func FormatDataToTitle(item interface{}) (interface{}, error) {
+	// the caser is stateful, which cannot be shared.
+	return formatString(item, cases.Title(language.Und).String)
+}
+
+func FormatDataToNFC(item interface{}) (interface{}, error) {
+	return formatString(item, norm.NFC.String)
+}
+
+func FormatDataToNFD(item interface{}) (interface{}, error) {
+	return formatString(item, norm.NFD.String)
+}
+
+func FormatDataToNFKC(item interface{}) (interface{}, error) {
+	return formatString(item, norm.NFKC.String)
+}
+
+func FormatDataToNFKD(item interface{}) (interface{}, error) {
+	return formatString(item, norm.NFKD.String)
+}
+
+// FormatDataCollapseWhitespace replaces each run of white spaces with a single space, and trims both ends.
+func FormatDataCollapseWhitespace(item interface{}) (interface{}, error) {
+	return formatString(item, func(str string) string {
+		return strings.Join(strings.Fields(str), " ")
+	})
+}
+
+// FormatDataStripControl removes the control characters except tab, line feed and carriage return.
+func FormatDataStripControl(item interface{}) (interface{}, error) {
+	return formatString(item, func(str string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+				return -1
+			}
+			return r
+		}, str)
+	})
+}
+
+// FormatDataStripZeroWidth removes the invisible characters, such as zero-width space and byte order mark.
+func FormatDataStripZeroWidth(item interface{}) (interface{}, error) {
+	return formatString(item, func(str string) string {
+		return strings.Map(func(r rune) rune {
+			switch r {
+			case '\u00AD', '\u180E', '\u200B', '\u200C', '\u200D', '\u2060', '\uFEFF':
+				return -1
+			default:
+				return r
+			}
+		}, str)
+	})
+}
+
+func BuildFormatDataTruncate(params map[string]interface{}) (FormatFunc, error) {
+	maxLength, err := paramInt(params, "max_length", 0)
+	if err != nil {
+		return nil, err
+	}
+	if maxLength <= 0 {
+		return nil, fmt.Errorf("illegal parameter max_length: %d", maxLength)
+	}
+
+	ellipsis, err := paramString(params, "ellipsis", "")
+	if err != nil {
+		return nil, err
+	}
+
+	ellipsisLength := utf8.RuneCountInString(ellipsis)
+	if ellipsisLength >= maxLength {
+		return nil, fmt.Errorf("ellipsis is longer than max_length")
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatString(item, func(str string) string {
+			if utf8.RuneCountInString(str) <= maxLength {
+				return str
+			}
+			runes := []rune(str)
+			return string(runes[:maxLength-ellipsisLength]) + ellipsis
+		})
+	}, nil
+}
+
+func formatString(item interface{}, f func(str string) string) (interface{}, error) {
+	str, ok := item.(string)
+	if !ok {
+		return item, nil
+	}
+	return f(str), nil
+}
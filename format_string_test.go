@@ -0,0 +1,35 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatString(t *testing.T) {
+	dir := "format_string"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := JSONSchemaFormat(source, template, StringFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	_, err = JSONSchemaFormat(source, []byte(`{"summary":{"__function":"truncate","max_length":2,"ellipsis":"..."}}`), StringFormatDataOptions...)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,257 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+const (
+	formatTemplateFunctionDirective    = "__function"
+	formatTemplateNullDirective        = "__null"
+	formatTemplateEmptyAsNullDirective = "__empty_as_null"
+	formatTemplateRedactDirective      = "__redact"
+	formatTemplateDecodeJSONDirective  = "__decode_json"
+	formatTemplateEncodeJSONDirective  = "__encode_json"
+	formatTemplateDefaultDirective     = "__default"
+)
+
+// defaultFormatBuilderMap is the built-in functions of template, which are available without options.
+var defaultFormatBuilderMap = map[string]FormatFuncBuilder{
+	FormatEnum:         BuildFormatDataEnum,
+	FormatConvertUnit:  BuildFormatDataConvertUnit,
+	FormatRegexReplace: BuildFormatDataRegexReplace,
+	FormatRegexExtract: BuildFormatDataRegexExtract,
+	FormatSplit:        BuildFormatDataSplit,
+	FormatJoin:         BuildFormatDataJoin,
+}
+
+// formatFuncNode is the compiled function leaf in template, which is a JSON object with the directive "__function",
+// e.g. {"__function":"to_time","layouts":["rfc3339"]}. The other keys of the object are the parameters of function.
+// The directives "__null" and "__empty_as_null" override the NullPolicy and EmptyAsNull of FormatConfig for this leaf,
+// and the directive "__default" is the value taken for null instead of invoking the function.
+type formatFuncNode struct {
+	name         string
+	params       map[string]interface{}
+	raw          map[string]interface{}
+	fn           FormatContextFunc
+	nullPolicy   string
+	emptyAsNull  *bool
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+func (node *formatFuncNode) format(ctx *FormatContext, item interface{}, config FormatConfig) (interface{}, error) {
+	if node.fn == nil {
+		return item, nil
+	}
+
+	if node.nullPolicy != "" {
+		config.NullPolicy = node.nullPolicy
+	}
+	if node.emptyAsNull != nil {
+		config.EmptyAsNull = *node.emptyAsNull
+	}
+	if node.hasDefault {
+		if str, ok := item.(string); item == nil || (ok && str == "" && config.EmptyAsNull) {
+			return node.defaultValue, nil
+		}
+	}
+	return formatNullable(node.fn, ctx, item, config)
+}
+
+// compileTemplate replaces the function leaves in template with formatFuncNode, so that the function with parameters
+// is built only once. It can be invoked again to rebuild the nodes when the options have changed.
+func compileTemplate(template interface{}, functionMap map[string]FormatContextFunc, builderMap map[string]FormatFuncBuilder) (interface{}, error) {
+	switch v := template.(type) {
+	case []interface{}:
+		for index, item := range v {
+			compiled, err := compileTemplate(item, functionMap, builderMap)
+			if err != nil {
+				return template, err
+			}
+			v[index] = compiled
+		}
+		return v, nil
+	case map[string]interface{}:
+		if _, ok := v[formatTemplateFunctionDirective]; ok {
+			return newFormatFuncNode(v, functionMap, builderMap)
+		}
+		if node, ok, err := newFormatEmbeddedNode(v); ok {
+			if err != nil {
+				return template, err
+			}
+			return compileTemplate(node, functionMap, builderMap)
+		}
+		for key, item := range v {
+			if key == formatTemplateRedactDirective {
+				compiled, err := newFormatRedactNode(item)
+				if err != nil {
+					return template, err
+				}
+				v[key] = compiled
+				continue
+			}
+
+			compiled, err := compileTemplate(item, functionMap, builderMap)
+			if err != nil {
+				return template, fmt.Errorf("%s: %s", key, err)
+			}
+			v[key] = compiled
+		}
+		return v, nil
+	case *formatFuncNode:
+		return newFormatFuncNode(v.raw, functionMap, builderMap)
+	case *formatEmbeddedNode:
+		compiled, err := compileTemplate(v.template, functionMap, builderMap)
+		if err != nil {
+			return template, fmt.Errorf("%s: %s", v.directive(), err)
+		}
+		v.template = compiled
+		return v, nil
+	default:
+		return template, nil
+	}
+}
+
+func newFormatFuncNode(raw map[string]interface{}, functionMap map[string]FormatContextFunc, builderMap map[string]FormatFuncBuilder) (*formatFuncNode, error) {
+	name, ok := raw[formatTemplateFunctionDirective].(string)
+	if !ok {
+		return nil, fmt.Errorf("illegal %s directive: %v", formatTemplateFunctionDirective, raw[formatTemplateFunctionDirective])
+	}
+
+	node := &formatFuncNode{name: name, params: make(map[string]interface{}), raw: raw}
+	for key, value := range raw {
+		switch key {
+		case formatTemplateFunctionDirective:
+		case formatTemplateNullDirective:
+			policy, ok := value.(string)
+			if !ok || !isNullPolicy(policy) {
+				return nil, fmt.Errorf("illegal %s directive: %v", formatTemplateNullDirective, value)
+			}
+			node.nullPolicy = policy
+		case formatTemplateEmptyAsNullDirective:
+			emptyAsNull, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("illegal %s directive: %v", formatTemplateEmptyAsNullDirective, value)
+			}
+			node.emptyAsNull = &emptyAsNull
+		case formatTemplateDefaultDirective:
+			node.defaultValue, node.hasDefault = value, true
+		default:
+			node.params[key] = value
+		}
+	}
+
+	builder, ok := builderMap[name]
+	if !ok {
+		builder, ok = defaultFormatBuilderMap[name]
+	}
+	if ok {
+		fn, err := builder(node.params)
+		if err != nil {
+			return nil, fmt.Errorf("build function %s failed: %s", name, err)
+		}
+		node.fn = adaptFormatFunc(fn)
+		return node, nil
+	}
+
+	// the unknown function is ignored as the one referred by name, the template may be updated before the options.
+	node.fn = functionMap[name]
+	return node, nil
+}
+
+// formatRedactNode is the compiled "__redact" directive in template, which is a regex pattern or a list of them.
+// The values whose keys match the patterns are replaced with RedactedValue, in the JSON object described by the
+// template and all its descendants.
+type formatRedactNode struct {
+	patterns []*regexp.Regexp
+}
+
+func newFormatRedactNode(directive interface{}) (*formatRedactNode, error) {
+	if node, ok := directive.(*formatRedactNode); ok {
+		return node, nil
+	}
+
+	var patterns []interface{}
+	switch v := directive.(type) {
+	case string:
+		patterns = append(patterns, v)
+	case []interface{}:
+		patterns = v
+	default:
+		return nil, fmt.Errorf("illegal %s directive: %v", formatTemplateRedactDirective, directive)
+	}
+
+	node := &formatRedactNode{}
+	for _, pattern := range patterns {
+		str, ok := pattern.(string)
+		if !ok {
+			return nil, fmt.Errorf("illegal %s directive: %v", formatTemplateRedactDirective, directive)
+		}
+		re, err := regexp.Compile(str)
+		if err != nil {
+			return nil, fmt.Errorf("illegal %s directive: %s", formatTemplateRedactDirective, err)
+		}
+		node.patterns = append(node.patterns, re)
+	}
+	return node, nil
+}
+
+// formatEmbeddedNode is the compiled "__decode_json" or "__encode_json" directive in template, e.g.
+// {"__decode_json":{"amount":"to_float64"}}. The directive "__decode_json" decodes the JSON serialized as a string and
+// formats it with the nested template, and "__encode_json" formats the value with the nested template and serializes it
+// to a string.
+type formatEmbeddedNode struct {
+	encode   bool
+	template interface{}
+}
+
+// newFormatEmbeddedNode takes the embedded JSON directive from the template object, ok is false if there isn't one.
+func newFormatEmbeddedNode(raw map[string]interface{}) (node *formatEmbeddedNode, ok bool, err error) {
+	for _, directive := range []string{formatTemplateDecodeJSONDirective, formatTemplateEncodeJSONDirective} {
+		template, exist := raw[directive]
+		if !exist {
+			continue
+		}
+		if len(raw) != 1 {
+			return nil, true, fmt.Errorf("illegal %s directive: %v", directive, raw)
+		}
+		return &formatEmbeddedNode{encode: directive == formatTemplateEncodeJSONDirective, template: template}, true, nil
+	}
+	return nil, false, nil
+}
+
+func (node *formatEmbeddedNode) directive() string {
+	if node.encode {
+		return formatTemplateEncodeJSONDirective
+	}
+	return formatTemplateDecodeJSONDirective
+}
+
+// decodeItem takes the JSON value serialized in item, the other values are returned as they are.
+func (node *formatEmbeddedNode) decodeItem(item interface{}, config FormatConfig) (interface{}, error) {
+	str, ok := item.(string)
+	if !ok {
+		return item, nil
+	}
+
+	decoded, err := unmarshalJSON([]byte(str), config)
+	if err != nil {
+		return item, fmt.Errorf("decode embedded JSON failed: %s", err)
+	}
+	return decoded, nil
+}
+
+// encodeItem serializes the formatted value to a string.
+func (node *formatEmbeddedNode) encodeItem(item interface{}) (interface{}, error) {
+	if _, ok := item.(formatDropped); ok {
+		return item, nil
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return item, fmt.Errorf("encode embedded JSON failed: %s", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,332 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	// the time zone names are loaded from the embedded database when the host has no tzdata.
+	_ "time/tzdata"
+
+	"github.com/Grivn/normalizejson/regex"
+)
+
+const (
+	FormatToTime         = "to_time"
+	FormatToRFC3339      = "to_rfc3339"
+	FormatToEpochSeconds = "to_epoch_seconds"
+	FormatToEpochMillis  = "to_epoch_millis"
+)
+
+// TimeFormatDataOptions are the data-options to normalize date and time.
+//
+// The function "to_time" takes the parameters below in a function leaf of template:
+//
+//   - "layouts": the layouts to parse a string one by one, which can be a Go layout or a name in timeLayoutMap.
+//     The name "epoch" (or "epoch_s", "epoch_ms", "epoch_us", "epoch_ns") parses the numbers as unix time.
+//     Defaults to ["rfc3339", "datetime", "2006-01-02 15:04", "2006-01-02T15:04:05", "date", "compact_date", "epoch"],
+//     the date layouts are tried before "epoch", so that "20240131" is parsed as a date.
+//   - "epoch_unit": the unit of the numbers parsed by "epoch", one of "s", "ms", "us", "ns" and "auto" (default),
+//     "auto" detects the unit by the magnitude of number.
+//   - "location": the time zone for the layouts without zone offset, such as "UTC" (default), "Asia/Shanghai", "+08:00".
+//   - "timezone": the time zone to convert to, the parsed time zone is retained by default.
+//   - "output": "rfc3339" (default), "rfc3339nano", "epoch_s", "epoch_ms", "epoch_us", "epoch_ns",
+//     or a Go layout or a name in timeLayoutMap to emit string.
+//
+// E.g. {"__function":"to_time","layouts":["2006-01-02 15:04"],"location":"Asia/Shanghai","timezone":"UTC"}.
+//
+// The functions "to_rfc3339", "to_epoch_seconds" and "to_epoch_millis" are "to_time" with the default parameters and
+// the output as their names.
+var TimeFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatToTime, BuildFormatDataToTime),
+	FormatDataOption(FormatToRFC3339, FormatDataToRFC3339),
+	FormatDataOption(FormatToEpochSeconds, FormatDataToEpochSeconds),
+	FormatDataOption(FormatToEpochMillis, FormatDataToEpochMillis),
+}
+
+var timeLayoutMap = map[string]string{
+	"rfc3339":      time.RFC3339,
+	"rfc3339nano":  time.RFC3339Nano,
+	"rfc1123":      time.RFC1123,
+	"rfc1123z":     time.RFC1123Z,
+	"rfc822":       time.RFC822,
+	"rfc822z":      time.RFC822Z,
+	"rfc850":       time.RFC850,
+	"ansic":        time.ANSIC,
+	"unix_date":    time.UnixDate,
+	"ruby_date":    time.RubyDate,
+	"datetime":     "2006-01-02 15:04:05",
+	"date":         "2006-01-02",
+	"compact_date": "20060102",
+}
+
+const (
+	timeLayoutEpoch = "epoch"
+
+	timeOutputRFC3339     = "rfc3339"
+	timeOutputRFC3339Nano = "rfc3339nano"
+	timeOutputEpochS      = "epoch_s"
+	timeOutputEpochMS     = "epoch_ms"
+	timeOutputEpochUS     = "epoch_us"
+	timeOutputEpochNS     = "epoch_ns"
+
+	timeEpochUnitAuto = "auto"
+)
+
+var defaultTimeLayouts = []string{"rfc3339", "datetime", "2006-01-02 15:04", "2006-01-02T15:04:05", "date", "compact_date", timeLayoutEpoch}
+
+var timeEpochUnitMap = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+func BuildFormatDataToTime(params map[string]interface{}) (FormatFunc, error) {
+	tf, err := newTimeFormatter(params)
+	if err != nil {
+		return nil, err
+	}
+	return tf.format, nil
+}
+
+// the formatters of the functions with the default parameters, which are built only once.
+var (
+	rfc3339TimeFormatter = mustNewTimeFormatter(timeOutputRFC3339)
+	epochSTimeFormatter  = mustNewTimeFormatter(timeOutputEpochS)
+	epochMSTimeFormatter = mustNewTimeFormatter(timeOutputEpochMS)
+)
+
+func mustNewTimeFormatter(output string) *timeFormatter {
+	tf, err := newTimeFormatter(map[string]interface{}{"output": output})
+	if err != nil {
+		panic(err)
+	}
+	return tf
+}
+
+func FormatDataToRFC3339(item interface{}) (interface{}, error) {
+	return rfc3339TimeFormatter.format(item)
+}
+
+func FormatDataToEpochSeconds(item interface{}) (interface{}, error) {
+	return epochSTimeFormatter.format(item)
+}
+
+func FormatDataToEpochMillis(item interface{}) (interface{}, error) {
+	return epochMSTimeFormatter.format(item)
+}
+
+type timeFormatter struct {
+	layouts   []string
+	epochUnit string
+	location  *time.Location
+	timezone  *time.Location
+	output    string
+}
+
+func newTimeFormatter(params map[string]interface{}) (*timeFormatter, error) {
+	tf := &timeFormatter{}
+
+	layouts, err := paramStringList(params, "layouts", defaultTimeLayouts)
+	if err != nil {
+		return nil, err
+	}
+	for _, layout := range layouts {
+		if layout == timeLayoutEpoch {
+			tf.layouts = append(tf.layouts, layout)
+			continue
+		}
+		if unit, ok := trimEpochOutput(layout); ok {
+			tf.layouts = append(tf.layouts, timeLayoutEpoch)
+			tf.epochUnit = unit
+			continue
+		}
+		if named, ok := timeLayoutMap[layout]; ok {
+			layout = named
+		}
+		tf.layouts = append(tf.layouts, layout)
+	}
+
+	if tf.epochUnit == "" {
+		if tf.epochUnit, err = paramString(params, "epoch_unit", timeEpochUnitAuto); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := timeEpochUnitMap[tf.epochUnit]; !ok && tf.epochUnit != timeEpochUnitAuto {
+		return nil, fmt.Errorf("unknown epoch unit %s", tf.epochUnit)
+	}
+
+	location, err := paramString(params, "location", "UTC")
+	if err != nil {
+		return nil, err
+	}
+	if tf.location, err = loadLocation(location); err != nil {
+		return nil, err
+	}
+
+	timezone, err := paramString(params, "timezone", "")
+	if err != nil {
+		return nil, err
+	}
+	if timezone != "" {
+		if tf.timezone, err = loadLocation(timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	if tf.output, err = paramString(params, "output", timeOutputRFC3339); err != nil {
+		return nil, err
+	}
+	return tf, nil
+}
+
+func (tf *timeFormatter) format(item interface{}) (interface{}, error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	t, err := tf.parse(item)
+	if err != nil {
+		return item, err
+	}
+
+	if tf.timezone != nil {
+		t = t.In(tf.timezone)
+	}
+
+	switch tf.output {
+	case timeOutputRFC3339:
+		return t.Format(time.RFC3339), nil
+	case timeOutputRFC3339Nano:
+		return t.Format(time.RFC3339Nano), nil
+	case timeOutputEpochS:
+		return t.Unix(), nil
+	case timeOutputEpochMS:
+		return t.UnixMilli(), nil
+	case timeOutputEpochUS:
+		return t.UnixMicro(), nil
+	case timeOutputEpochNS:
+		return t.UnixNano(), nil
+	default:
+		if named, ok := timeLayoutMap[tf.output]; ok {
+			return t.Format(named), nil
+		}
+		return t.Format(tf.output), nil
+	}
+}
+
+func (tf *timeFormatter) parse(item interface{}) (time.Time, error) {
+	var str string
+	switch v := item.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		str = strings.TrimSpace(v)
+	case json.Number:
+		str = v.String()
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		str = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		str = fmt.Sprint(v)
+	default:
+		return time.Time{}, fmt.Errorf("unable to parse %#v of type %T as time", item, item)
+	}
+
+	for _, layout := range tf.layouts {
+		if layout == timeLayoutEpoch {
+			if !regex.Number.MatchString(str) {
+				continue
+			}
+			return tf.parseEpoch(str)
+		}
+
+		t, err := time.ParseInLocation(layout, str, tf.location)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse %#v of type %T as time", item, item)
+}
+
+func (tf *timeFormatter) parseEpoch(str string) (time.Time, error) {
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	unit, ok := timeEpochUnitMap[tf.epochUnit]
+	if !ok {
+		unit = detectEpochUnit(f)
+	}
+
+	// take the integer part exactly, the float number loses precision for the large epoch.
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(str, "+"), ".")
+	i, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	switch unit {
+	case time.Second:
+		t = time.Unix(i, 0)
+	case time.Millisecond:
+		t = time.UnixMilli(i)
+	case time.Microsecond:
+		t = time.UnixMicro(i)
+	default:
+		t = time.Unix(0, i)
+	}
+
+	if frac != "" {
+		fraction, _ := strconv.ParseFloat("0."+frac, 64)
+		if strings.HasPrefix(str, "-") {
+			fraction = -fraction
+		}
+		t = t.Add(time.Duration(math.Round(fraction * float64(unit))))
+	}
+	return t.In(tf.location), nil
+}
+
+func detectEpochUnit(epoch float64) time.Duration {
+	switch abs := math.Abs(epoch); {
+	case abs < 1e11:
+		return time.Second
+	case abs < 1e14:
+		return time.Millisecond
+	case abs < 1e17:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+func trimEpochOutput(layout string) (string, bool) {
+	switch layout {
+	case timeOutputEpochS, timeOutputEpochMS, timeOutputEpochUS, timeOutputEpochNS:
+		return strings.TrimPrefix(layout, timeLayoutEpoch+"_"), true
+	default:
+		return "", false
+	}
+}
+
+// loadLocation loads a time zone by name, or by offset such as "+08:00" and "-0700".
+func loadLocation(name string) (*time.Location, error) {
+	match := regex.TimeOffset.FindStringSubmatch(name)
+	if match == nil {
+		return time.LoadLocation(name)
+	}
+
+	hours, _ := strconv.Atoi(match[2])
+	minutes, _ := strconv.Atoi(match[3])
+	offset := (hours*60 + minutes) * 60
+	if match[1] == "-" {
+		offset = -offset
+	}
+	return time.FixedZone(name, offset), nil
+}
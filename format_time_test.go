@@ -0,0 +1,77 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTime(t *testing.T) {
+	dir := "format_time"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := JSONSchemaFormat(source, template, TimeFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+
+	_, err = JSONSchemaFormat([]byte(`{"created_at":"yesterday"}`), template, TimeFormatDataOptions...)
+	assert.NotNil(t, err)
+
+	_, err = JSONSchemaFormat(source, []byte(`{"created_at":{"__function":"to_time","epoch_unit":"day"}}`), TimeFormatDataOptions...)
+	assert.NotNil(t, err)
+
+	// the compact dates are parsed by the date layouts before epoch.
+	formatted, err = JSONSchemaFormat([]byte(`{"created_at":"20240131","updated_at":20240131}`), template, TimeFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"created_at":"2024-01-31T00:00:00Z","updated_at":1706659200}`)), formatJSON(formatted))
+}
+
+func TestFormatTimeDataProvider(t *testing.T) {
+	dir := "format_time"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	// the template is updated before the options, the function leaves are built when the options are added.
+	provider, err := NewFormatDataProvider(template)
+	if err != nil {
+		panic(err)
+	}
+	provider.AddOptions(TimeFormatDataOptions...)
+
+	formatted, err := provider.FormatJSONSchema(source)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
@@ -0,0 +1,179 @@
+package normalizejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// the layouts of the local datetimes, dates and times of TOML, by the names of locations taken in decoding.
+var tomlLocalTimeLayouts = map[string]string{
+	"datetime-local": "2006-01-02T15:04:05.999999999",
+	"date-local":     "2006-01-02",
+	"time-local":     "15:04:05.999999999",
+}
+
+// FormatTOML normalizes the TOML document with provider, so that the same templates and options work for TOML.
+// The datetimes are taken as strings, e.g. RFC3339 for the offset datetimes and "2006-01-02" for the local dates,
+// and written back as datetimes if they are still in the same layout. The null values are omitted since TOML has
+// no null, and the keys are sorted in the result, in which the comments of source are not preserved.
+func FormatTOML(provider FormatProvider, data []byte) ([]byte, error) {
+	var original map[string]interface{}
+	if err := toml.Unmarshal(data, &original); err != nil {
+		return nil, fmt.Errorf("unmarshal source TOML failed: %s", err)
+	}
+
+	value, err := formatValueByProvider(provider, tomlToJSONValue(original))
+	if err != nil {
+		return nil, err
+	}
+	return marshalTOML(tomlValue(value, original))
+}
+
+// TOMLToJSON converts the TOML document to JSON.
+func TOMLToJSON(data []byte) ([]byte, error) {
+	var value map[string]interface{}
+	if err := toml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("unmarshal source TOML failed: %s", err)
+	}
+
+	converted, err := json.Marshal(tomlToJSONValue(value))
+	if err != nil {
+		return nil, fmt.Errorf("marshal JSON failed: %s", err)
+	}
+	return converted, nil
+}
+
+// JSONToTOML converts the JSON object to TOML, the keys are sorted and the null values are omitted.
+func JSONToTOML(data []byte) ([]byte, error) {
+	value, err := unmarshalJSON(data, FormatConfig{UseNumber: true})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	}
+	return marshalTOML(tomlValue(value, nil))
+}
+
+func marshalTOML(value interface{}) ([]byte, error) {
+	if _, ok := value.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("marshal TOML failed: the document should be an object, but got %T", value)
+	}
+
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = ""
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("marshal TOML failed: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlToJSONValue converts the value decoded from TOML to the JSON value model, without changing the value.
+func tomlToJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = tomlToJSONValue(item)
+		}
+		return converted
+	case []map[string]interface{}:
+		converted := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			converted = append(converted, tomlToJSONValue(item))
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			converted = append(converted, tomlToJSONValue(item))
+		}
+		return converted
+	case time.Time:
+		if layout, ok := tomlLocalTimeLayouts[v.Location().String()]; ok {
+			return v.Format(layout)
+		}
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// tomlValue converts the JSON value to the value to be encoded as TOML, and takes the strings as datetimes if the
+// original values are datetimes in the same layout. The original value may be nil.
+func tomlValue(value interface{}, original interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		originalMap, _ := original.(map[string]interface{})
+		originalKeys := sortedKeys(originalMap)
+		converted := make(map[string]interface{}, len(v))
+		used := make(map[string]bool, len(originalKeys))
+		for _, key := range sortedKeys(v) {
+			if v[key] == nil {
+				continue
+			}
+			var originalItem interface{}
+			if originalKey, ok := matchFormattedKey(key, originalKeys, used); ok {
+				used[originalKey] = true
+				originalItem = originalMap[originalKey]
+			}
+			converted[key] = tomlValue(v[key], originalItem)
+		}
+		return converted
+	case []interface{}:
+		originalList := tomlList(original)
+		if len(originalList) != len(v) {
+			originalList = nil
+		}
+		converted := make([]interface{}, 0, len(v))
+		for index, item := range v {
+			var originalItem interface{}
+			if originalList != nil {
+				originalItem = originalList[index]
+			}
+			converted = append(converted, tomlValue(item, originalItem))
+		}
+		return converted
+	case string:
+		originalTime, ok := original.(time.Time)
+		if !ok {
+			return v
+		}
+		if layout, ok := tomlLocalTimeLayouts[originalTime.Location().String()]; ok {
+			if _, err := time.Parse(layout, v); err == nil {
+				return tomlLocalTime(v)
+			}
+			return v
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func tomlList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, item)
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// tomlLocalTime is the local datetime, date or time of TOML, which is written as is without the time zone.
+type tomlLocalTime string
+
+func (t tomlLocalTime) MarshalTOML() ([]byte, error) {
+	return []byte(t), nil
+}
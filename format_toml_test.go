@@ -0,0 +1,68 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTOML(t *testing.T) {
+	dir := "format_toml"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.toml")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.toml")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatTOML(provider, source)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, string(result), string(formatted))
+}
+
+func TestFormatTOMLLargeIntegers(t *testing.T) {
+	// the integers beyond 2^53 are exact, whatever the config of provider is.
+	provider, err := NewFormatProvider([]byte(`{"count":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatTOML(provider, []byte("bigId = 9007199254740993\ncount = \"9007199254740995\"\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "bigId = 9007199254740993\ncount = 9007199254740995\n", string(formatted))
+}
+
+func TestTOMLToJSON(t *testing.T) {
+	converted, err := TOMLToJSON([]byte("id = 1\nday = 2024-01-02\nat = 07:32:00\n[labels]\nname = \"one\"\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"id":1,"day":"2024-01-02","at":"07:32:00","labels":{"name":"one"}}`)), formatJSON(converted))
+
+	converted, err = JSONToTOML([]byte(`{"name":"12","rate":1.5,"items":[1,2],"none":null,"sub":{"ok":true}}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "items = [1, 2]\nname = \"12\"\nrate = 1.5\n\n[sub]\nok = true\n", string(converted))
+
+	_, err = JSONToTOML([]byte(`[1,2]`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,240 @@
+package normalizejson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Grivn/normalizejson/regex"
+)
+
+const (
+	FormatConvertUnit = "convert_unit"
+)
+
+const (
+	unitOutputNumber = "number"
+	unitOutputString = "string"
+)
+
+const (
+	unitDimensionLength      = "length"
+	unitDimensionMass        = "mass"
+	unitDimensionTemperature = "temperature"
+	unitDimensionDataSize    = "data_size"
+	unitDimensionDuration    = "duration"
+)
+
+// measureUnit converts the values of the unit to the base unit of its dimension by value*factor+offset.
+type measureUnit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// measureUnitMap is the units with their symbols and names, the base units are meter, kilogram, kelvin, byte and second.
+var measureUnitMap = map[string]measureUnit{}
+
+// measureUnitFoldMap is the units by the lowercase symbols, except the ones which are ambiguous in lowercase and the
+// symbols of data size, whose case tells byte from bit, e.g. "MB" and "Mb".
+var measureUnitFoldMap = map[string]measureUnit{}
+
+func init() {
+	caseSensitive := make(map[string]bool)
+	registerMeasureUnit := func(dimension string, factor float64, offset float64, names ...string) {
+		for _, name := range names {
+			measureUnitMap[name] = measureUnit{dimension: dimension, factor: factor, offset: offset}
+		}
+	}
+	registerCaseSensitiveUnit := func(dimension string, factor float64, names ...string) {
+		registerMeasureUnit(dimension, factor, 0, names...)
+		for _, name := range names {
+			caseSensitive[name] = true
+		}
+	}
+
+	registerMeasureUnit(unitDimensionLength, 1, 0, "m", "meter", "meters", "metre", "metres")
+	registerMeasureUnit(unitDimensionLength, 1e3, 0, "km", "kilometer", "kilometers")
+	registerMeasureUnit(unitDimensionLength, 1e-2, 0, "cm", "centimeter", "centimeters")
+	registerMeasureUnit(unitDimensionLength, 1e-3, 0, "mm", "millimeter", "millimeters")
+	registerMeasureUnit(unitDimensionLength, 1e-6, 0, "um", "µm", "micrometer", "micrometers")
+	registerMeasureUnit(unitDimensionLength, 1e-9, 0, "nm", "nanometer", "nanometers")
+	registerMeasureUnit(unitDimensionLength, 0.0254, 0, "in", "inch", "inches")
+	registerMeasureUnit(unitDimensionLength, 0.3048, 0, "ft", "foot", "feet")
+	registerMeasureUnit(unitDimensionLength, 0.9144, 0, "yd", "yard", "yards")
+	registerMeasureUnit(unitDimensionLength, 1609.344, 0, "mi", "mile", "miles")
+	registerMeasureUnit(unitDimensionLength, 1852, 0, "nmi", "nautical_mile", "nautical_miles")
+
+	registerMeasureUnit(unitDimensionMass, 1, 0, "kg", "kilogram", "kilograms")
+	registerMeasureUnit(unitDimensionMass, 1e-3, 0, "g", "gram", "grams")
+	registerMeasureUnit(unitDimensionMass, 1e-6, 0, "mg", "milligram", "milligrams")
+	registerMeasureUnit(unitDimensionMass, 1e-9, 0, "ug", "µg", "microgram", "micrograms")
+	registerMeasureUnit(unitDimensionMass, 1e3, 0, "t", "tonne", "tonnes")
+	registerMeasureUnit(unitDimensionMass, 0.45359237, 0, "lb", "lbs", "pound", "pounds")
+	registerMeasureUnit(unitDimensionMass, 0.028349523125, 0, "oz", "ounce", "ounces")
+	registerMeasureUnit(unitDimensionMass, 6.35029318, 0, "st", "stone", "stones")
+
+	registerMeasureUnit(unitDimensionTemperature, 1, 0, "K", "kelvin")
+	registerMeasureUnit(unitDimensionTemperature, 1, 273.15, "C", "°C", "celsius")
+	registerMeasureUnit(unitDimensionTemperature, 5.0/9, 273.15-32*5.0/9, "F", "°F", "fahrenheit")
+
+	registerMeasureUnit(unitDimensionDataSize, 1.0/8, 0, "bit", "bits")
+	registerMeasureUnit(unitDimensionDataSize, 1, 0, "byte", "bytes")
+	registerCaseSensitiveUnit(unitDimensionDataSize, 1, "B")
+	for index, prefix := range []string{"K", "M", "G", "T", "P"} {
+		registerCaseSensitiveUnit(unitDimensionDataSize, math.Pow(1000, float64(index+1)), prefix+"B")
+		registerCaseSensitiveUnit(unitDimensionDataSize, math.Pow(1024, float64(index+1)), prefix+"iB")
+		registerCaseSensitiveUnit(unitDimensionDataSize, math.Pow(1000, float64(index+1))/8, prefix+"b", prefix+"bit")
+		registerCaseSensitiveUnit(unitDimensionDataSize, math.Pow(1024, float64(index+1))/8, prefix+"ib", prefix+"ibit")
+	}
+	registerCaseSensitiveUnit(unitDimensionDataSize, 1e3, "kB")
+	registerCaseSensitiveUnit(unitDimensionDataSize, 1e3/8, "kb", "kbit")
+
+	registerMeasureUnit(unitDimensionDuration, 1e-9, 0, "ns", "nanosecond", "nanoseconds")
+	registerMeasureUnit(unitDimensionDuration, 1e-6, 0, "us", "µs", "microsecond", "microseconds")
+	registerMeasureUnit(unitDimensionDuration, 1e-3, 0, "ms", "millisecond", "milliseconds")
+	registerMeasureUnit(unitDimensionDuration, 1, 0, "s", "sec", "second", "seconds")
+	registerMeasureUnit(unitDimensionDuration, 60, 0, "min", "minute", "minutes")
+	registerMeasureUnit(unitDimensionDuration, 3600, 0, "h", "hr", "hour", "hours")
+	registerMeasureUnit(unitDimensionDuration, 86400, 0, "d", "day", "days")
+	registerMeasureUnit(unitDimensionDuration, 604800, 0, "w", "week", "weeks")
+
+	ambiguous := make(map[string]bool)
+	for name, unit := range measureUnitMap {
+		if caseSensitive[name] {
+			continue
+		}
+		folded := strings.ToLower(name)
+		if exist, ok := measureUnitFoldMap[folded]; ok && exist != unit {
+			ambiguous[folded] = true
+		}
+		measureUnitFoldMap[folded] = unit
+	}
+	for folded := range ambiguous {
+		delete(measureUnitFoldMap, folded)
+	}
+}
+
+func takeMeasureUnit(name string) (measureUnit, error) {
+	if unit, ok := measureUnitMap[name]; ok {
+		return unit, nil
+	}
+	if unit, ok := measureUnitFoldMap[strings.ToLower(name)]; ok {
+		return unit, nil
+	}
+	return measureUnit{}, fmt.Errorf("unknown unit %s", name)
+}
+
+// BuildFormatDataConvertUnit creates the function to convert the measurements, which is a built-in function of
+// template and requires no option. It takes the numbers, or the strings with optional unit suffix such as "12.5 MB"
+// and "98.6 °F", and converts them among the units of length, mass, temperature, data size and duration.
+// The parameters in function leaf of template:
+//
+//   - "to": the target unit, which is required.
+//   - "from": the source unit for the values without unit suffix.
+//   - "precision": the number of decimal places to round to, no rounding by default.
+//   - "output": "number" (default), or "string" with the target unit as suffix, e.g. "11.92 MiB".
+//
+// E.g. {"__function":"convert_unit","from":"F","to":"C","precision":1}.
+func BuildFormatDataConvertUnit(params map[string]interface{}) (FormatFunc, error) {
+	toName, err := paramString(params, "to", "")
+	if err != nil {
+		return nil, err
+	}
+	if toName == "" {
+		return nil, fmt.Errorf("no target unit")
+	}
+	to, err := takeMeasureUnit(toName)
+	if err != nil {
+		return nil, err
+	}
+
+	var from *measureUnit
+	fromName, err := paramString(params, "from", "")
+	if err != nil {
+		return nil, err
+	}
+	if fromName != "" {
+		unit, err := takeMeasureUnit(fromName)
+		if err != nil {
+			return nil, err
+		}
+		if unit.dimension != to.dimension {
+			return nil, fmt.Errorf("unable to convert %s to %s", fromName, toName)
+		}
+		from = &unit
+	}
+
+	precision, err := paramInt(params, "precision", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := paramString(params, "output", unitOutputNumber)
+	if err != nil {
+		return nil, err
+	}
+	if output != unitOutputNumber && output != unitOutputString {
+		return nil, fmt.Errorf("unknown unit output %s", output)
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		value, unit, err := parseMeasurement(item, from)
+		if err != nil {
+			return item, err
+		}
+		if unit.dimension != to.dimension {
+			return item, fmt.Errorf("unable to convert %v to %s", item, toName)
+		}
+
+		converted := (value*unit.factor + unit.offset - to.offset) / to.factor
+		if precision >= 0 {
+			scale := math.Pow(10, float64(precision))
+			converted = math.Round(converted*scale) / scale
+		}
+
+		if output == unitOutputString {
+			return strconv.FormatFloat(converted, 'f', -1, 64) + " " + toName, nil
+		}
+		return converted, nil
+	}, nil
+}
+
+// parseMeasurement takes the value and unit of a measurement, the unit suffix of string overrides the source unit.
+func parseMeasurement(item interface{}, from *measureUnit) (float64, measureUnit, error) {
+	switch v := item.(type) {
+	case string:
+		matches := regex.Measurement.FindStringSubmatch(v)
+		if matches == nil {
+			return 0, measureUnit{}, fmt.Errorf("unable to parse measurement %s", v)
+		}
+
+		value, err := strconv.ParseFloat(matches[1], 64)
+		if err != nil {
+			return 0, measureUnit{}, fmt.Errorf("unable to parse measurement %s", v)
+		}
+
+		if matches[2] != "" {
+			unit, err := takeMeasureUnit(matches[2])
+			return value, unit, err
+		}
+		if from == nil {
+			return 0, measureUnit{}, fmt.Errorf("no unit in measurement %s", v)
+		}
+		return value, *from, nil
+	case json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		if from == nil {
+			return 0, measureUnit{}, fmt.Errorf("no unit in measurement %v", v)
+		}
+
+		value, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		if err != nil {
+			return 0, measureUnit{}, fmt.Errorf("unable to parse measurement %v", v)
+		}
+		return value, *from, nil
+	default:
+		return 0, measureUnit{}, fmt.Errorf("unable to cast %#v of type %T to measurement", item, item)
+	}
+}
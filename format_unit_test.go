@@ -0,0 +1,65 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatUnit(t *testing.T) {
+	dir := "format_unit"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := JSONSchemaFormat(source, template)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestFormatUnitError(t *testing.T) {
+	template := []byte(`{"size":{"__function":"convert_unit","to":"MB"}}`)
+
+	for _, source := range []string{`{"size":1024}`, `{"size":"12 kg"}`, `{"size":"12 parsecs"}`, `{"size":"large"}`} {
+		_, err := JSONSchemaFormat([]byte(source), template)
+		assert.NotNil(t, err, source)
+	}
+
+	for _, template := range []string{
+		`{"size":{"__function":"convert_unit"}}`,
+		`{"size":{"__function":"convert_unit","from":"kg","to":"MB"}}`,
+		`{"size":{"__function":"convert_unit","to":"MB","output":"text"}}`,
+	} {
+		_, err := JSONSchemaFormat([]byte(`{"size":1024}`), []byte(template))
+		assert.NotNil(t, err, template)
+	}
+}
+
+func TestFormatUnitDataSize(t *testing.T) {
+	// the symbols of data size are case-sensitive, "Mb" is megabit rather than megabyte.
+	template := []byte(`{"a":{"__function":"convert_unit","to":"MB"},"b":{"__function":"convert_unit","to":"MB"},"c":{"__function":"convert_unit","to":"KB"},"d":{"__function":"convert_unit","to":"B"}}`)
+	formatted, err := JSONSchemaFormat([]byte(`{"a":"8 Mb","b":"2 MB","c":"8 kbit","d":"2 Bytes"}`), template)
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"a":1,"b":2,"c":1,"d":2}`)), formatJSON(formatted))
+
+	for _, source := range []string{`{"a":"2 mb"}`, `{"a":"2 mB"}`} {
+		_, err = JSONSchemaFormat([]byte(source), template)
+		assert.NotNil(t, err, source)
+	}
+}
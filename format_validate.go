@@ -0,0 +1,278 @@
+package normalizejson
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/Grivn/normalizejson/regex"
+	"github.com/spf13/cast"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+const (
+	FormatEmail        = "email"
+	FormatURL          = "url"
+	FormatUUID         = "uuid"
+	FormatIP           = "ip"
+	FormatPhoneE164    = "phone_e164"
+	FormatCountryCode  = "country_code"
+	FormatCurrencyCode = "currency_code"
+)
+
+const (
+	ipVersion4 = "ipv4"
+	ipVersion6 = "ipv6"
+
+	countryCodeAlpha2 = "alpha2"
+	countryCodeAlpha3 = "alpha3"
+)
+
+// ValidateFormatDataOptions are the data-options to validate the values and convert them to the canonical forms.
+// The invalid values are regarded as errors, which are FormatError with the paths of the values.
+//
+// The parameters in function leaf of template:
+//
+//   - "email": lowercase the whole address, or only the domain with "lowercase" as false.
+//   - "url": the absolute URL with host, lowercase the scheme and host and remove the default port. "schemes" limits
+//     the allowed schemes, e.g. ["http","https"].
+//   - "uuid": accept the UUIDs with or without hyphens, braces and "urn:uuid:" prefix, and output the lowercase
+//     hyphenated form.
+//   - "ip": the canonical text of IPv4 or IPv6 address, "version" limits it to "ipv4" or "ipv6".
+//   - "phone_e164": the phone number in E.164 form, e.g. "+14155552671". The number without "+" or "00" prefix takes
+//     the "default_country_code", whose national trunk prefix "0" is removed.
+//   - "country_code": the ISO-3166 code from alpha-2, alpha-3 or numeric code, "output" is "alpha2" (default) or "alpha3".
+//   - "currency_code": the ISO-4217 currency code.
+//
+// E.g. {"__function":"phone_e164","default_country_code":"44"}.
+var ValidateFormatDataOptions = []FormatOption{
+	FormatDataBuilderOption(FormatEmail, BuildFormatDataEmail),
+	FormatDataBuilderOption(FormatURL, BuildFormatDataURL),
+	FormatDataBuilderOption(FormatUUID, BuildFormatDataUUID),
+	FormatDataBuilderOption(FormatIP, BuildFormatDataIP),
+	FormatDataBuilderOption(FormatPhoneE164, BuildFormatDataPhoneE164),
+	FormatDataBuilderOption(FormatCountryCode, BuildFormatDataCountryCode),
+	FormatDataBuilderOption(FormatCurrencyCode, BuildFormatDataCurrencyCode),
+}
+
+func BuildFormatDataEmail(params map[string]interface{}) (FormatFunc, error) {
+	lowercase, err := paramBool(params, "lowercase", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatEmail, func(str string) (string, bool) {
+			addr, err := mail.ParseAddress(str)
+			if err != nil || addr.Name != "" || addr.Address != str {
+				return "", false
+			}
+
+			at := strings.LastIndex(str, "@")
+			if lowercase {
+				return strings.ToLower(str), true
+			}
+			return str[:at] + strings.ToLower(str[at:]), true
+		})
+	}, nil
+}
+
+func BuildFormatDataURL(params map[string]interface{}) (FormatFunc, error) {
+	schemes, err := paramStringList(params, "schemes", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatURL, func(str string) (string, bool) {
+			u, err := url.Parse(str)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return "", false
+			}
+
+			u.Scheme = strings.ToLower(u.Scheme)
+			if len(schemes) > 0 && !containsFold(schemes, u.Scheme) {
+				return "", false
+			}
+
+			host, port := strings.ToLower(u.Hostname()), u.Port()
+			if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+				port = ""
+			}
+			switch {
+			case port != "":
+				u.Host = net.JoinHostPort(host, port)
+			case strings.Contains(host, ":"):
+				u.Host = "[" + host + "]"
+			default:
+				u.Host = host
+			}
+			return u.String(), true
+		})
+	}, nil
+}
+
+func BuildFormatDataUUID(params map[string]interface{}) (FormatFunc, error) {
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatUUID, func(str string) (string, bool) {
+			matches := regex.UUID.FindStringSubmatch(str)
+			if matches == nil {
+				return "", false
+			}
+			return strings.ToLower(strings.Join(matches[1:], "-")), true
+		})
+	}, nil
+}
+
+func BuildFormatDataIP(params map[string]interface{}) (FormatFunc, error) {
+	version, err := paramString(params, "version", "")
+	if err != nil {
+		return nil, err
+	}
+	if version != "" && version != ipVersion4 && version != ipVersion6 {
+		return nil, fmt.Errorf("unknown IP version %s", version)
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatIP, func(str string) (string, bool) {
+			addr, err := netip.ParseAddr(str)
+			if err != nil {
+				return "", false
+			}
+
+			switch version {
+			case ipVersion4:
+				addr = addr.Unmap()
+				if !addr.Is4() {
+					return "", false
+				}
+			case ipVersion6:
+				if !addr.Is6() {
+					return "", false
+				}
+			}
+			return addr.String(), true
+		})
+	}, nil
+}
+
+func BuildFormatDataPhoneE164(params map[string]interface{}) (FormatFunc, error) {
+	defaultCountryCode, err := paramString(params, "default_country_code", "")
+	if err != nil {
+		return nil, err
+	}
+	defaultCountryCode = strings.TrimPrefix(defaultCountryCode, "+")
+	if defaultCountryCode != "" && !isPhoneDigits(defaultCountryCode) {
+		return nil, fmt.Errorf("illegal parameter default_country_code: %s", defaultCountryCode)
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatPhoneE164, func(str string) (string, bool) {
+			digits := regex.PhoneSeparator.ReplaceAllString(str, "")
+			switch {
+			case strings.HasPrefix(digits, "+"):
+				digits = digits[1:]
+			case strings.HasPrefix(digits, "00"):
+				digits = digits[2:]
+			case defaultCountryCode != "":
+				digits = defaultCountryCode + strings.TrimPrefix(digits, "0")
+			default:
+				return "", false
+			}
+
+			// E.164 numbers have at most 15 digits and the country codes never start with 0.
+			if !isPhoneDigits(digits) || digits[0] == '0' || len(digits) < 7 || len(digits) > 15 {
+				return "", false
+			}
+			return "+" + digits, true
+		})
+	}, nil
+}
+
+func BuildFormatDataCountryCode(params map[string]interface{}) (FormatFunc, error) {
+	output, err := paramString(params, "output", countryCodeAlpha2)
+	if err != nil {
+		return nil, err
+	}
+	if output != countryCodeAlpha2 && output != countryCodeAlpha3 {
+		return nil, fmt.Errorf("unknown country code output %s", output)
+	}
+
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatCountryCode, func(str string) (string, bool) {
+			region, err := language.ParseRegion(str)
+			if err != nil {
+				return "", false
+			}
+
+			// the region table of CLDR contains the continents and private use codes, which are not countries.
+			region = region.Canonicalize()
+			if !region.IsCountry() || region.IsPrivateUse() || region.ISO3() == "ZZZ" {
+				return "", false
+			}
+
+			if output == countryCodeAlpha3 {
+				return region.ISO3(), true
+			}
+			return region.String(), true
+		})
+	}, nil
+}
+
+func BuildFormatDataCurrencyCode(params map[string]interface{}) (FormatFunc, error) {
+	return func(item interface{}) (interface{}, error) {
+		return formatValidated(item, FormatCurrencyCode, func(str string) (string, bool) {
+			unit, err := currency.ParseISO(str)
+			if err != nil {
+				return "", false
+			}
+			return unit.String(), true
+		})
+	}, nil
+}
+
+// formatValidated validates the trimmed string of a JSON scalar value and takes its canonical form.
+func formatValidated(item interface{}, name string, f func(str string) (string, bool)) (interface{}, error) {
+	switch item.(type) {
+	case nil:
+		return nil, nil
+	case bool, map[string]interface{}, []interface{}:
+		return item, fmt.Errorf("invalid %s %v", name, item)
+	}
+
+	str, err := cast.ToStringE(item)
+	if err != nil {
+		return item, fmt.Errorf("invalid %s %v", name, item)
+	}
+
+	formatted, ok := f(strings.TrimSpace(str))
+	if !ok {
+		return item, fmt.Errorf("invalid %s %v", name, item)
+	}
+	return formatted, nil
+}
+
+func isPhoneDigits(str string) bool {
+	if str == "" {
+		return false
+	}
+	for _, r := range str {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(strList []string, str string) bool {
+	for _, s := range strList {
+		if strings.EqualFold(s, str) {
+			return true
+		}
+	}
+	return false
+}
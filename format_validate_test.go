@@ -0,0 +1,68 @@
+package normalizejson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatValidate(t *testing.T) {
+	dir := "format_validate"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.json")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := JSONSchemaFormat(source, template, ValidateFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestFormatValidateError(t *testing.T) {
+	template := []byte(`{"contacts":[{"email":"email","profile":{"home page":"url"}}],"rate":{"currency":"currency_code"}}`)
+
+	cases := []struct {
+		source []byte
+		path   string
+	}{
+		{source: []byte(`{"contacts":[{"email":"alice@example.com"},{"email":"not an email"}]}`), path: "$.contacts[1].email"},
+		{source: []byte(`{"contacts":[{"profile":{"home page":"example.com"}}]}`), path: `$.contacts[0].profile["home page"]`},
+		{source: []byte(`{"rate":{"currency":"ABC"}}`), path: "$.rate.currency"},
+	}
+
+	for _, c := range cases {
+		for _, format := range []func(data []byte, rawTemplate []byte, options ...FormatOption) ([]byte, error){JSONSchemaFormat, JSONSchemaFormatData} {
+			_, err := format(c.source, template, ValidateFormatDataOptions...)
+
+			var formatErr *FormatError
+			if assert.True(t, errors.As(err, &formatErr)) {
+				assert.Equal(t, c.path, formatErr.Path)
+			}
+		}
+	}
+
+	for template, source := range map[string]string{
+		`{"ip":{"__function":"ip","version":"ipv4"}}`:          `{"ip":"::1"}`,
+		`{"phone":"phone_e164"}`:                               `{"phone":"020 7946 0958"}`,
+		`{"country":"country_code"}`:                           `{"country":"EU"}`,
+		`{"id":"uuid"}`:                                        `{"id":"6ba7b810-9dad-11d1-80b4"}`,
+		`{"website":{"__function":"url","schemes":["https"]}}`: `{"website":"ftp://example.com"}`,
+	} {
+		_, err := JSONSchemaFormat([]byte(source), []byte(template), ValidateFormatDataOptions...)
+		assert.NotNil(t, err, template)
+	}
+}
@@ -0,0 +1,304 @@
+package normalizejson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	DefaultXMLAttributePrefix = "@"
+	DefaultXMLTextKey         = "#text"
+)
+
+// XMLConfig configures the conversion of XML, the zero value of each field takes the default one.
+type XMLConfig struct {
+	AttributePrefix string // the prefix of the keys of attributes, DefaultXMLAttributePrefix by default.
+	TextKey         string // the key of the text of elements with attributes or children, DefaultXMLTextKey by default.
+}
+
+func (config XMLConfig) attributePrefix() string {
+	if config.AttributePrefix == "" {
+		return DefaultXMLAttributePrefix
+	}
+	return config.AttributePrefix
+}
+
+func (config XMLConfig) textKey() string {
+	if config.TextKey == "" {
+		return DefaultXMLTextKey
+	}
+	return config.TextKey
+}
+
+// FormatXML converts the XML document to JSON as XMLToJSON does, and normalizes it with provider.
+// The elements described by arrays in the template of schema or data provider are taken as arrays even if they occur
+// once, e.g. {"order":{"item":["__template.item"]}} takes <order><item>...</item></order> as {"order":{"item":[...]}}.
+// The providers out of this package take the elements as XMLToJSON does.
+func FormatXML(provider FormatProvider, data []byte, config XMLConfig) ([]byte, error) {
+	value, err := xmlToJSONValue(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := provider.(type) {
+	case *formatSchemaImpl:
+		value, err = p.forceArrays(value)
+	case *formatDataImpl:
+		value, err = p.forceArrays(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal JSON failed: %s", err)
+	}
+	return provider.FormatJSONSchema(jsonData)
+}
+
+// XMLToJSON converts the XML document to a JSON object keyed by the root element, in which the values are strings.
+// The elements and attributes are keyed by their local names without namespaces, and the repeated elements are taken
+// as arrays, while the attributes of an element with the same local name are regarded as an error.
+// The elements with neither attributes nor children are taken as their texts, or null if empty, and the others are
+// taken as objects whose attributes are keyed with the prefix and whose text is keyed by the text key.
+func XMLToJSON(data []byte, config XMLConfig) ([]byte, error) {
+	value, err := xmlToJSONValue(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal JSON failed: %s", err)
+	}
+	return converted, nil
+}
+
+// xmlElement is an element in decoding, whose value is built once it ends.
+type xmlElement struct {
+	name     string
+	value    map[string]interface{}
+	children map[string]int // the numbers of children by names.
+	text     strings.Builder
+}
+
+func xmlToJSONValue(data []byte, config XMLConfig) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var stack []*xmlElement
+	var root map[string]interface{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("unmarshal source XML failed: %s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root != nil {
+				return nil, fmt.Errorf("unmarshal source XML failed: multiple root elements")
+			}
+			element := &xmlElement{name: t.Name.Local, value: make(map[string]interface{}), children: make(map[string]int)}
+			for _, attr := range t.Attr {
+				// the declarations of namespaces are dropped.
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				key := config.attributePrefix() + attr.Name.Local
+				if _, ok := element.value[key]; ok {
+					return nil, fmt.Errorf("unmarshal source XML failed: duplicate attribute %s of element %s", attr.Name.Local, t.Name.Local)
+				}
+				element.value[key] = attr.Value
+			}
+			stack = append(stack, element)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			element := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			value := element.jsonValue(config)
+			if len(stack) == 0 {
+				root = map[string]interface{}{element.name: value}
+				continue
+			}
+			stack[len(stack)-1].addChild(element.name, value)
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("unmarshal source XML failed: missing root element")
+	}
+	return root, nil
+}
+
+// addChild adds the value of child element, the repeated ones are collected in an array.
+func (element *xmlElement) addChild(name string, value interface{}) {
+	switch element.children[name] {
+	case 0:
+		element.value[name] = value
+	case 1:
+		element.value[name] = []interface{}{element.value[name], value}
+	default:
+		element.value[name] = append(element.value[name].([]interface{}), value)
+	}
+	element.children[name]++
+}
+
+func (element *xmlElement) jsonValue(config XMLConfig) interface{} {
+	text := strings.TrimSpace(element.text.String())
+	if len(element.value) == 0 {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+
+	if text != "" {
+		element.value[config.textKey()] = text
+	}
+	return element.value
+}
+
+// forceArrays wraps the values described by arrays in template into arrays, as formatItem finds the templates.
+func (fsi *formatSchemaImpl) forceArrays(item interface{}) (interface{}, error) {
+	if fsi.templateErr != nil {
+		return nil, fmt.Errorf("compile template failed: %s", fsi.templateErr)
+	}
+
+	scope, err := fsi.takeScope(newFormatScope(context.Background(), item), fsi.templateMap)
+	if err != nil {
+		return nil, fmt.Errorf("format JSON data failed: %s", err)
+	}
+	return fsi.forceArraysOfItem(scope, item, nil), nil
+}
+
+func (fsi *formatSchemaImpl) forceArraysOfItem(scope formatScope, item interface{}, template interface{}) interface{} {
+	template = fsi.takeTemplate(template)
+
+	switch v := item.(type) {
+	case []interface{}:
+		var elementTemplate interface{}
+		if templateList, ok := template.([]interface{}); ok && len(templateList) > 0 {
+			elementTemplate = templateList[0]
+		}
+		for index, element := range v {
+			v[index] = fsi.forceArraysOfItem(scope, element, elementTemplate)
+		}
+		return v
+	case map[string]interface{}:
+		templateMap, ok := template.(map[string]interface{})
+		if ok {
+			var err error
+			if scope, err = fsi.takeScope(scope, templateMap); err != nil {
+				// the error is reported in formatting.
+				return v
+			}
+		}
+
+		for key, child := range v {
+			formattedKey, err := fsi.formatKey(scope, key)
+			if err != nil || isFormatDirective(formattedKey) {
+				continue
+			}
+
+			var childTemplate interface{}
+			if templateMap != nil {
+				childTemplate = templateOfKey(templateMap, formattedKey)
+			} else {
+				childTemplate = fsi.templateMap[formattedKey]
+			}
+
+			if _, ok := fsi.takeTemplate(childTemplate).([]interface{}); ok {
+				if _, ok := child.([]interface{}); !ok {
+					child = []interface{}{child}
+				}
+			}
+			v[key] = fsi.forceArraysOfItem(scope, child, childTemplate)
+		}
+		return v
+	default:
+		return item
+	}
+}
+
+// forceArrays wraps the values described by arrays in template into arrays, as formatItem finds the templates.
+func (fdi *formatDataImpl) forceArrays(item interface{}) (interface{}, error) {
+	if fdi.templateErr != nil {
+		return nil, fmt.Errorf("compile template failed: %s", fdi.templateErr)
+	}
+	return fdi.forceArraysOfItem(item), nil
+}
+
+// forceArraysOfItem takes the templates of root by keys as formatItem does.
+func (fdi *formatDataImpl) forceArraysOfItem(item interface{}) interface{} {
+	switch v := item.(type) {
+	case []interface{}:
+		for index, element := range v {
+			v[index] = fdi.forceArraysOfItem(element)
+		}
+		return v
+	case map[string]interface{}:
+		for key, child := range v {
+			if template, ok := fdi.templateMap[key]; ok {
+				v[key] = fdi.forceArraysByTemplate(child, template)
+			} else {
+				v[key] = fdi.forceArraysOfItem(child)
+			}
+		}
+		return v
+	default:
+		return item
+	}
+}
+
+// forceArraysByTemplate takes the templates as formatItemByTemplate does.
+func (fdi *formatDataImpl) forceArraysByTemplate(item interface{}, template interface{}) interface{} {
+	switch v := template.(type) {
+	case string:
+		if !needTemplate(v) {
+			return item
+		}
+		template, ok := fdi.templateMap[strings.TrimPrefix(v, formatDataTemplatePrefix)]
+		if !ok {
+			return item
+		}
+		return fdi.forceArraysByTemplate(item, template)
+	case []interface{}:
+		itemList, ok := item.([]interface{})
+		if !ok {
+			itemList = []interface{}{item}
+		}
+		if len(v) == 0 {
+			return itemList
+		}
+		for index, element := range itemList {
+			itemList[index] = fdi.forceArraysByTemplate(element, v[0])
+		}
+		return itemList
+	case map[string]interface{}:
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			return item
+		}
+		for key, childTemplate := range v {
+			if child, ok := itemMap[key]; ok {
+				itemMap[key] = fdi.forceArraysByTemplate(child, childTemplate)
+			}
+		}
+		return itemMap
+	default:
+		return item
+	}
+}
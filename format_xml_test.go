@@ -0,0 +1,75 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatXML(t *testing.T) {
+	dir := "format_xml"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.xml")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.json")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatXML(provider, source, XMLConfig{})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON(result), formatJSON(formatted))
+}
+
+func TestFormatXMLDataProvider(t *testing.T) {
+	// the data provider takes the elements described by arrays as arrays, as the schema provider does.
+	template := []byte(`{"order":{"item":["__template.item"]},"item":{"qty":"to_int64"}}`)
+	source := []byte(`<order><item><qty>2</qty></item></order>`)
+	for _, create := range []func([]byte, ...FormatOption) (FormatProvider, error){NewFormatSchemaProvider, NewFormatDataProvider} {
+		provider, err := create(template, DefaultFormatDataOptions...)
+		if err != nil {
+			panic(err)
+		}
+
+		formatted, err := FormatXML(provider, source, XMLConfig{})
+		if err != nil {
+			panic(err)
+		}
+		assert.Equal(t, formatJSON([]byte(`{"order":{"item":[{"qty":2}]}}`)), formatJSON(formatted))
+	}
+}
+
+func TestXMLToJSON(t *testing.T) {
+	converted, err := XMLToJSON([]byte(`<a x="1"><b>one</b><b>two</b><c y="2">text</c><d></d></a>`), XMLConfig{AttributePrefix: "-", TextKey: "_"})
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"a":{"-x":"1","b":["one","two"],"c":{"-y":"2","_":"text"},"d":null}}`)), formatJSON(converted))
+
+	_, err = XMLToJSON([]byte(`<a><b></a>`), XMLConfig{})
+	assert.NotNil(t, err)
+
+	_, err = XMLToJSON([]byte(`<a/><b/>`), XMLConfig{})
+	assert.NotNil(t, err)
+
+	_, err = XMLToJSON([]byte(``), XMLConfig{})
+	assert.NotNil(t, err)
+
+	// the attributes with the same local name in different namespaces collide.
+	_, err = XMLToJSON([]byte(`<a xmlns:x="urn:x" xmlns:y="urn:y" x:id="1" y:id="2"/>`), XMLConfig{})
+	assert.NotNil(t, err)
+}
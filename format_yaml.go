@@ -0,0 +1,273 @@
+package normalizejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatYAML normalizes the YAML documents with provider, so that the same templates and options work for YAML.
+// The non-string keys of YAML are taken as strings, e.g. 1 as "1", and the timestamps as RFC3339 strings.
+// The comments, the order of keys and the styles of unchanged values are preserved where possible, and the renamed
+// keys are matched with the original ones ignoring case and separators, e.g. "userId" and "user_id".
+func FormatYAML(provider FormatProvider, data []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("unmarshal source YAML failed: %s", err)
+		}
+
+		jsonData, err := yamlDocumentToJSON(&doc)
+		if err != nil {
+			return nil, err
+		}
+
+		formatted, err := formatJSONExactly(provider, jsonData)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := unmarshalJSON(formatted, FormatConfig{UseNumber: true})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(doc.Content) == 0 {
+			doc.Content = []*yaml.Node{yamlNode(value, nil)}
+		} else {
+			doc.Content[0] = yamlNode(value, doc.Content[0])
+		}
+		if err = encoder.Encode(&doc); err != nil {
+			return nil, fmt.Errorf("marshal YAML failed: %s", err)
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshal YAML failed: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// YAMLToJSON converts the first YAML document to JSON.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal source YAML failed: %s", err)
+	}
+	return yamlDocumentToJSON(&doc)
+}
+
+// JSONToYAML converts the JSON document to YAML, the keys are sorted.
+func JSONToYAML(data []byte) ([]byte, error) {
+	value, err := unmarshalJSON(data, FormatConfig{UseNumber: true})
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal source data failed: %s", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(yamlNode(value, nil)); err != nil {
+		return nil, fmt.Errorf("marshal YAML failed: %s", err)
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshal YAML failed: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func yamlDocumentToJSON(doc *yaml.Node) ([]byte, error) {
+	var value interface{}
+	if err := doc.Decode(&value); err != nil {
+		return nil, fmt.Errorf("unmarshal source YAML failed: %s", err)
+	}
+
+	value, err := yamlToJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// yamlToJSONValue converts the value decoded from YAML to the JSON value model.
+func yamlToJSONValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			converted, err := yamlToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			switch key.(type) {
+			case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+				return nil, fmt.Errorf("unsupported YAML key %v", key)
+			}
+
+			convertedItem, err := yamlToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			convertedKey := "null"
+			if key != nil {
+				convertedKey = fmt.Sprint(key)
+			}
+			converted[convertedKey] = convertedItem
+		}
+		return converted, nil
+	case []interface{}:
+		for index, item := range v {
+			converted, err := yamlToJSONValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[index] = converted
+		}
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	default:
+		return v, nil
+	}
+}
+
+// yamlNode builds the YAML node of the JSON value, and takes the comments, the order of keys and the styles of
+// unchanged values from the original node, which may be nil.
+func yamlNode(value interface{}, original *yaml.Node) *yaml.Node {
+	if original != nil && original.Kind == yaml.AliasNode {
+		original = nil
+	}
+
+	var node *yaml.Node
+	switch v := value.(type) {
+	case map[string]interface{}:
+		node = yamlMappingNode(v, original)
+	case []interface{}:
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		var originalItems []*yaml.Node
+		if original != nil && original.Kind == yaml.SequenceNode && len(original.Content) == len(v) {
+			originalItems = original.Content
+		}
+		for index, item := range v {
+			var originalItem *yaml.Node
+			if originalItems != nil {
+				originalItem = originalItems[index]
+			}
+			node.Content = append(node.Content, yamlNode(item, originalItem))
+		}
+	default:
+		if original != nil && original.Kind == yaml.ScalarNode && isYAMLScalarUnchanged(original, value) {
+			unchanged := *original
+			return &unchanged
+		}
+		node = yamlScalarNode(value)
+	}
+
+	if original != nil {
+		node.HeadComment = original.HeadComment
+		node.LineComment = original.LineComment
+		node.FootComment = original.FootComment
+		// take the flow style of the original mapping or sequence.
+		if node.Kind != yaml.ScalarNode && node.Kind == original.Kind {
+			node.Style = original.Style
+		}
+	}
+	return node
+}
+
+func yamlMappingNode(itemMap map[string]interface{}, original *yaml.Node) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+
+	keys := sortedKeys(itemMap)
+
+	// take the keys in the original order at first, then the new ones.
+	used := make(map[string]bool, len(itemMap))
+	if original != nil && original.Kind == yaml.MappingNode {
+		for index := 0; index+1 < len(original.Content); index += 2 {
+			keyNode, valueNode := original.Content[index], original.Content[index+1]
+			key, ok := matchFormattedKey(keyNode.Value, keys, used)
+			if !ok {
+				continue
+			}
+			used[key] = true
+
+			formattedKeyNode := *keyNode
+			if key != keyNode.Value {
+				formattedKeyNode.Value = key
+				formattedKeyNode.Tag = "!!str"
+				formattedKeyNode.Style = 0
+			}
+			node.Content = append(node.Content, &formattedKeyNode, yamlNode(itemMap[key], valueNode))
+		}
+	}
+
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		node.Content = append(node.Content, yamlScalarNode(key), yamlNode(itemMap[key], nil))
+	}
+	return node
+}
+
+func yamlScalarNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		if v {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "false"}
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: v.String()}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+	}
+}
+
+// isYAMLScalarUnchanged checks whether the scalar node holds the same JSON value.
+func isYAMLScalarUnchanged(node *yaml.Node, value interface{}) bool {
+	var originalValue interface{}
+	if err := node.Decode(&originalValue); err != nil {
+		return false
+	}
+
+	originalValue, err := yamlToJSONValue(originalValue)
+	if err != nil {
+		return false
+	}
+
+	originalData, err := json.Marshal(originalValue)
+	if err != nil {
+		return false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(originalData, data)
+}
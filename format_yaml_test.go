@@ -0,0 +1,68 @@
+package normalizejson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatYAML(t *testing.T) {
+	dir := "format_yaml"
+	template, err := readTestData(dir, "config.json")
+	if err != nil {
+		panic(err)
+	}
+
+	source, err := readTestData(dir, "source.yaml")
+	if err != nil {
+		panic(err)
+	}
+
+	result, err := readTestData(dir, "result.yaml")
+	if err != nil {
+		panic(err)
+	}
+
+	provider, err := NewFormatProvider(template, DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatYAML(provider, source)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Equal(t, string(result), string(formatted))
+}
+
+func TestFormatYAMLLargeIntegers(t *testing.T) {
+	// the integers beyond 2^53 are exact, whatever the config of provider is.
+	provider, err := NewFormatProvider([]byte(`{"count":"to_int64"}`), DefaultFormatDataOptions...)
+	if err != nil {
+		panic(err)
+	}
+
+	formatted, err := FormatYAML(provider, []byte("bigId: 9007199254740993\ncount: \"9007199254740995\"\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "bigId: 9007199254740993\ncount: 9007199254740995\n", string(formatted))
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	converted, err := YAMLToJSON([]byte("id: 1\nlabels:\n  1: one\n  true: yes\ncreated: 2024-01-02T03:04:05Z\n"))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, formatJSON([]byte(`{"id":1,"labels":{"1":"one","true":"yes"},"created":"2024-01-02T03:04:05Z"}`)), formatJSON(converted))
+
+	converted, err = JSONToYAML([]byte(`{"name":"12","rate":12345678901234567890.5,"items":[1,{"ok":true}],"none":null}`))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal(t, "items:\n  - 1\n  - ok: true\nname: \"12\"\nnone: null\nrate: 12345678901234567890.5\n", string(converted))
+
+	_, err = YAMLToJSON([]byte("? [a, b]\n: c\n"))
+	assert.NotNil(t, err)
+}
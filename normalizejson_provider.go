@@ -1,5 +1,7 @@
 package normalizejson
 
+import "context"
+
 type FormatProvider interface {
 	AddOptions(options ...FormatOption)
 	UpdateTemplate(rawTemplate []byte) error
@@ -7,6 +9,32 @@ type FormatProvider interface {
 	Reset()
 }
 
+// ConfigurableProvider is the FormatProvider whose FormatConfig can be updated, which all the built-in providers are,
+// e.g. provider.(normalizejson.ConfigurableProvider).UpdateConfig(normalizejson.FormatConfig{UseNumber: true}).
+type ConfigurableProvider interface {
+	FormatProvider
+	UpdateConfig(config FormatConfig)
+}
+
+// ContextFormatProvider is the FormatProvider which takes a context.Context to abort the normalization when it is
+// cancelled, which all the built-in providers are.
+type ContextFormatProvider interface {
+	FormatProvider
+	FormatJSONSchemaContext(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// formatJSONSchemaContext normalizes data with the context if provider takes it, otherwise the context is checked
+// only before the normalization.
+func formatJSONSchemaContext(ctx context.Context, provider FormatProvider, data []byte) ([]byte, error) {
+	if p, ok := provider.(ContextFormatProvider); ok {
+		return p.FormatJSONSchemaContext(ctx, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return provider.FormatJSONSchema(data)
+}
+
 func NewFormatProvider(rawTemplate []byte, options ...FormatOption) (FormatProvider, error) {
 	return NewFormatSchemaProvider(rawTemplate, options...)
 }
@@ -4,4 +4,10 @@ var (
 	CamelCase        = regexCamelCase
 	JSONKey          = regexJSONKey
 	CamelCaseJSONKey = regexCamelCaseJSONKey
+	Number           = regexNumber
+	Decimal          = regexDecimal
+	Measurement      = regexMeasurement
+	TimeOffset       = regexTimeOffset
+	UUID             = regexUUID
+	PhoneSeparator   = regexPhoneSeparator
 )
@@ -0,0 +1,7 @@
+package regex
+
+import "regexp"
+
+var regexDecimal = regexp.MustCompile(`^([-+]?)(\d*)(?:\.(\d*))?(?:[eE]([-+]?\d+))?$`) // decimal number with optional exponent
+
+var regexMeasurement = regexp.MustCompile(`^\s*([-+]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][-+]?\d+)?)\s*(\S.*?)?\s*$`) // number with optional unit suffix
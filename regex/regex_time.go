@@ -0,0 +1,7 @@
+package regex
+
+import "regexp"
+
+var regexNumber = regexp.MustCompile(`^[-+]?\d+(\.\d+)?$`) // decimal number
+
+var regexTimeOffset = regexp.MustCompile(`^([-+])(\d{2}):?(\d{2})$`) // time zone offset
@@ -0,0 +1,7 @@
+package regex
+
+import "regexp"
+
+var regexUUID = regexp.MustCompile(`^(?i)(?:urn:uuid:)?\{?([0-9a-f]{8})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{12})\}?$`) // UUID with or without hyphens
+
+var regexPhoneSeparator = regexp.MustCompile(`[\s\-.()/]`) // separators in phone number